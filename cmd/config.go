@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// fileConfig is the configuration file given by --config.
+// Flags given explicitly on the command line take priority over the values in this file.
+type fileConfig struct {
+	Resources  resourcesConfig       `json:"resources"`
+	Thresholds []thresholdRuleConfig `json:"thresholds,omitempty"`
+}
+
+type resourcesConfig struct {
+	// Include and Exclude are resource patterns in the same form as --include-resources and --exclude-resources.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// thresholdRuleConfig is a rule to override --threshold for matching objects.
+// Group, Kind and Namespace accept the same patterns as --include-namespaces, and empty fields match any object.
+type thresholdRuleConfig struct {
+	Name      string          `json:"name"`
+	Group     string          `json:"group,omitempty"`
+	Kind      string          `json:"kind,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Selector  string          `json:"selector,omitempty"`
+	Threshold metav1.Duration `json:"threshold"`
+}
+
+func loadConfig(path string) (*fileConfig, error) {
+	cfg := &fileConfig{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
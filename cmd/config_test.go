@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+	cfg, err := loadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, &fileConfig{}, cfg)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	err = os.WriteFile(path, []byte(`resources:
+  include:
+  - example.com
+  exclude:
+  - metrics.k8s.io
+  - example.com/v1alpha1/*
+`), 0644)
+	require.NoError(t, err)
+	cfg, err = loadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com"}, cfg.Resources.Include)
+	assert.Equal(t, []string{"metrics.k8s.io", "example.com/v1alpha1/*"}, cfg.Resources.Exclude)
+
+	err = os.WriteFile(path, []byte(`resource:
+  include:
+  - example.com
+`), 0644)
+	require.NoError(t, err)
+	_, err = loadConfig(path)
+	assert.Error(t, err)
+
+	_, err = loadConfig(filepath.Join(dir, "not-found.yaml"))
+	assert.Error(t, err)
+}
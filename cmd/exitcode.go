@@ -0,0 +1,32 @@
+package cmd
+
+import "fmt"
+
+// Exit codes with --exit-code.
+const (
+	exitClean      = 0
+	exitZombies    = 1
+	exitIncomplete = 2
+	exitFatal      = 3
+)
+
+// exitError terminates the program with code after the result is reported.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// reportExitCode returns the exit code for a report.
+// Zombies found have priority over an incomplete scan, because they need action regardless of the rest of the cluster.
+func reportExitCode(r *report) int {
+	switch {
+	case len(r.Zombies) > 0:
+		return exitZombies
+	case r.incomplete():
+		return exitIncomplete
+	}
+	return exitClean
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportExitCode(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	incomplete := scanFailures{lists: []listFailure{{Version: "v1", Resource: "secrets", Reason: "Forbidden"}}}
+
+	clean := newReport(nil, nil, "", time.Hour, now, now)
+	assert.Equal(t, exitClean, reportExitCode(clean))
+
+	suppressedOnly := newTestReport()
+	suppressedOnly.Zombies = []zombieEntry{}
+	assert.Equal(t, exitClean, reportExitCode(suppressedOnly))
+
+	assert.Equal(t, exitZombies, reportExitCode(newTestReport()))
+
+	r := newReport(nil, nil, "", time.Hour, now, now)
+	r.addFailures(incomplete)
+	assert.Equal(t, exitIncomplete, reportExitCode(r))
+
+	r = newReport(nil, nil, "", time.Hour, now, now)
+	r.addFailures(scanFailures{discovery: []discoveryFailure{{GroupVersion: "metrics.k8s.io/v1beta1"}}})
+	assert.Equal(t, exitIncomplete, reportExitCode(r))
+
+	r = newTestReport()
+	r.addFailures(incomplete)
+	assert.Equal(t, exitZombies, reportExitCode(r))
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// namePattern matches names with a glob pattern, or with a regular expression if it is enclosed in slashes like "/^kube-.*$/".
+type namePattern struct {
+	glob   string
+	regexp *regexp.Regexp
+}
+
+func parseNamePattern(s string) (namePattern, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return namePattern{}, fmt.Errorf("invalid regular expression %q: %w", s, err)
+		}
+		return namePattern{regexp: re}, nil
+	}
+	if _, err := path.Match(s, ""); err != nil {
+		return namePattern{}, fmt.Errorf("invalid glob pattern %q: %w", s, err)
+	}
+	return namePattern{glob: s}, nil
+}
+
+func parseNamePatterns(ss []string) ([]namePattern, error) {
+	patterns := make([]namePattern, 0, len(ss))
+	for _, s := range ss {
+		p, err := parseNamePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func (p namePattern) match(name string) bool {
+	if p.regexp != nil {
+		return p.regexp.MatchString(name)
+	}
+	matched, _ := path.Match(p.glob, name)
+	return matched
+}
+
+func matchAny(patterns []namePattern, name string) bool {
+	for _, p := range patterns {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceFilter decides which objects are scanned by their namespace.
+// The zero value scans all objects.
+type namespaceFilter struct {
+	// namespace limits listing of namespaced resources to a single namespace.
+	namespace string
+	include   []namePattern
+	exclude   []namePattern
+	// selector selects namespaces by their labels.
+	// selected is resolved from it by listing namespaces at the beginning of a scan.
+	selector labels.Selector
+	selected map[string]bool
+	// skipClusterScoped makes cluster-scoped resources not scanned.
+	skipClusterScoped bool
+}
+
+func (f *namespaceFilter) match(namespace string) bool {
+	if f.namespace != "" && namespace != f.namespace {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, namespace) {
+		return false
+	}
+	if matchAny(f.exclude, namespace) {
+		return false
+	}
+	if f.selected != nil && !f.selected[namespace] {
+		return false
+	}
+	return true
+}
+
+// resourcePattern matches resources in the form of "GROUP", "GROUP/RESOURCE" or "GROUP/VERSION/RESOURCE".
+// Each part can contain glob wildcards. The core group is written as "core" or an empty string like "/pods".
+type resourcePattern struct {
+	group    string
+	version  string
+	resource string
+}
+
+func parseResourcePattern(s string) (resourcePattern, error) {
+	p := resourcePattern{version: "*", resource: "*"}
+	parts := strings.Split(s, "/")
+	switch len(parts) {
+	case 1:
+		p.group = parts[0]
+	case 2:
+		p.group, p.resource = parts[0], parts[1]
+	case 3:
+		p.group, p.version, p.resource = parts[0], parts[1], parts[2]
+	default:
+		return p, fmt.Errorf("invalid resource pattern %q, expected GROUP, GROUP/RESOURCE or GROUP/VERSION/RESOURCE", s)
+	}
+	if p.group == "core" {
+		p.group = ""
+	}
+	for _, part := range []string{p.group, p.version, p.resource} {
+		if _, err := path.Match(part, ""); err != nil {
+			return p, fmt.Errorf("invalid resource pattern %q: %w", s, err)
+		}
+	}
+	return p, nil
+}
+
+func parseResourcePatterns(ss []string) ([]resourcePattern, error) {
+	patterns := make([]resourcePattern, 0, len(ss))
+	for _, s := range ss {
+		p, err := parseResourcePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func (p resourcePattern) match(gvr schema.GroupVersionResource) bool {
+	for _, pair := range [][2]string{{p.group, gvr.Group}, {p.version, gvr.Version}, {p.resource, gvr.Resource}} {
+		if matched, _ := path.Match(pair[0], pair[1]); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchGroupVersion returns true if the pattern can match some resources of gv.
+func (p resourcePattern) matchGroupVersion(gv schema.GroupVersion) bool {
+	for _, pair := range [][2]string{{p.group, gv.Group}, {p.version, gv.Version}} {
+		if matched, _ := path.Match(pair[0], pair[1]); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceFilter decides which resources are scanned.
+// The zero value scans all resources.
+type resourceFilter struct {
+	include []resourcePattern
+	exclude []resourcePattern
+}
+
+func (f *resourceFilter) included(gvr schema.GroupVersionResource) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.match(gvr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *resourceFilter) excluded(gvr schema.GroupVersionResource) bool {
+	for _, p := range f.exclude {
+		if p.match(gvr) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipsGroupVersion returns true if no resource of gv is scanned, that is, no include pattern matches gv or an exclude pattern matches all resources of gv.
+// It is used to ignore discovery failures of group versions that would not be scanned anyway.
+func (f *resourceFilter) skipsGroupVersion(gv schema.GroupVersion) bool {
+	if len(f.include) > 0 && !slices.ContainsFunc(f.include, func(p resourcePattern) bool { return p.matchGroupVersion(gv) }) {
+		return true
+	}
+	return slices.ContainsFunc(f.exclude, func(p resourcePattern) bool {
+		return p.resource == "*" && p.matchGroupVersion(gv)
+	})
+}
+
+type annotationRequirement struct {
+	key      string
+	operator selection.Operator
+	value    string
+}
+
+// annotationSelector matches objects by their annotations on the client side.
+// Unlike label selectors, annotation values are not restricted, so the syntax is limited to
+// comma-separated "key", "!key", "key=value" and "key!=value".
+type annotationSelector []annotationRequirement
+
+func parseAnnotationSelector(s string) (annotationSelector, error) {
+	if s == "" {
+		return nil, nil
+	}
+	selector := make(annotationSelector, 0)
+	for _, term := range strings.Split(s, ",") {
+		req := annotationRequirement{}
+		if key, value, found := strings.Cut(term, "!="); found {
+			req = annotationRequirement{key: key, operator: selection.NotEquals, value: value}
+		} else if key, value, found := strings.Cut(term, "="); found {
+			req = annotationRequirement{key: key, operator: selection.Equals, value: value}
+		} else if key, found := strings.CutPrefix(term, "!"); found {
+			req = annotationRequirement{key: key, operator: selection.DoesNotExist}
+		} else {
+			req = annotationRequirement{key: term, operator: selection.Exists}
+		}
+		req.key = strings.TrimSpace(req.key)
+		if req.key == "" {
+			return nil, fmt.Errorf("invalid annotation selector %q", s)
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+func (s annotationSelector) match(annotations map[string]string) bool {
+	for _, req := range s {
+		value, ok := annotations[req.key]
+		switch req.operator {
+		case selection.Equals:
+			if !ok || value != req.value {
+				return false
+			}
+		case selection.NotEquals:
+			if ok && value == req.value {
+				return false
+			}
+		case selection.Exists:
+			if !ok {
+				return false
+			}
+		case selection.DoesNotExist:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNamePattern(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "default", name: "default", want: true},
+		{pattern: "default", name: "default-2", want: false},
+		{pattern: "kube-*", name: "kube-system", want: true},
+		{pattern: "kube-*", name: "my-kube-system", want: false},
+		{pattern: "tenant-?", name: "tenant-a", want: true},
+		{pattern: "tenant-?", name: "tenant-ab", want: false},
+		{pattern: "/^kube-/", name: "kube-public", want: true},
+		{pattern: "/^kube-/", name: "my-kube-public", want: false},
+		{pattern: "/system/", name: "kube-system", want: true},
+		{pattern: "/", name: "/", want: true},
+	} {
+		p, err := parseNamePattern(tt.pattern)
+		require.NoError(t, err, tt.pattern)
+		assert.Equal(t, tt.want, p.match(tt.name), "pattern %q, name %q", tt.pattern, tt.name)
+	}
+
+	_, err := parseNamePattern("[a-")
+	assert.Error(t, err)
+	_, err = parseNamePattern("/(/")
+	assert.Error(t, err)
+}
+
+func TestNamespaceFilter(t *testing.T) {
+	t.Parallel()
+	mustParse := func(ss ...string) []namePattern {
+		patterns, err := parseNamePatterns(ss)
+		require.NoError(t, err)
+		return patterns
+	}
+	for _, tt := range []struct {
+		name      string
+		filter    namespaceFilter
+		namespace string
+		want      bool
+	}{
+		{
+			name:      "zero value matches everything",
+			filter:    namespaceFilter{},
+			namespace: "default",
+			want:      true,
+		},
+		{
+			name:      "single namespace",
+			filter:    namespaceFilter{namespace: "test"},
+			namespace: "default",
+			want:      false,
+		},
+		{
+			name:      "included",
+			filter:    namespaceFilter{include: mustParse("tenant-*", "default")},
+			namespace: "tenant-a",
+			want:      true,
+		},
+		{
+			name:      "not included",
+			filter:    namespaceFilter{include: mustParse("tenant-*", "default")},
+			namespace: "kube-system",
+			want:      false,
+		},
+		{
+			name:      "excluded",
+			filter:    namespaceFilter{exclude: mustParse("/^kube-/")},
+			namespace: "kube-system",
+			want:      false,
+		},
+		{
+			name:      "exclusion has priority over inclusion",
+			filter:    namespaceFilter{include: mustParse("tenant-*"), exclude: mustParse("tenant-b")},
+			namespace: "tenant-b",
+			want:      false,
+		},
+		{
+			name:      "selected by label selector",
+			filter:    namespaceFilter{selected: map[string]bool{"tenant-a": true}},
+			namespace: "tenant-a",
+			want:      true,
+		},
+		{
+			name:      "not selected by label selector",
+			filter:    namespaceFilter{selected: map[string]bool{"tenant-a": true}},
+			namespace: "tenant-b",
+			want:      false,
+		},
+	} {
+		assert.Equal(t, tt.want, tt.filter.match(tt.namespace), tt.name)
+	}
+}
+
+func TestResourcePattern(t *testing.T) {
+	t.Parallel()
+	pods := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	podMetrics := schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	for _, tt := range []struct {
+		pattern string
+		gvr     schema.GroupVersionResource
+		want    bool
+	}{
+		{pattern: "core", gvr: pods, want: true},
+		{pattern: "", gvr: pods, want: true},
+		{pattern: "core", gvr: deployments, want: false},
+		{pattern: "core/pods", gvr: pods, want: true},
+		{pattern: "/pods", gvr: pods, want: true},
+		{pattern: "core/pods", gvr: podMetrics, want: false},
+		{pattern: "*/pods", gvr: podMetrics, want: true},
+		{pattern: "metrics.k8s.io", gvr: podMetrics, want: true},
+		{pattern: "*.k8s.io", gvr: podMetrics, want: true},
+		{pattern: "metrics.k8s.io/v1beta1/pods", gvr: podMetrics, want: true},
+		{pattern: "metrics.k8s.io/v1/pods", gvr: podMetrics, want: false},
+		{pattern: "metrics.k8s.io/v1*/*", gvr: podMetrics, want: true},
+		{pattern: "apps/v1/deploy*", gvr: deployments, want: true},
+		{pattern: "*", gvr: deployments, want: true},
+	} {
+		p, err := parseResourcePattern(tt.pattern)
+		require.NoError(t, err, tt.pattern)
+		assert.Equal(t, tt.want, p.match(tt.gvr), "pattern %q, gvr %s", tt.pattern, tt.gvr)
+	}
+
+	for _, pattern := range []string{"a/b/c/d", "[a-/pods", "apps/[v"} {
+		_, err := parseResourcePattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestResourceFilter(t *testing.T) {
+	t.Parallel()
+	include, err := parseResourcePatterns([]string{"example.com", "core/pods"})
+	require.NoError(t, err)
+	exclude, err := parseResourcePatterns([]string{"example.com/flaky*"})
+	require.NoError(t, err)
+	f := resourceFilter{include: include, exclude: exclude}
+
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	flaky := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "flakywidgets"}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+	assert.True(t, f.included(widgets))
+	assert.False(t, f.excluded(widgets))
+	assert.True(t, f.excluded(flaky))
+	assert.True(t, f.included(pods))
+	assert.False(t, f.included(secrets))
+
+	zero := resourceFilter{}
+	assert.True(t, zero.included(secrets))
+	assert.False(t, zero.excluded(secrets))
+}
+
+func TestResourceFilterSkipsGroupVersion(t *testing.T) {
+	t.Parallel()
+	metrics := schema.GroupVersion{Group: "metrics.k8s.io", Version: "v1beta1"}
+	example := schema.GroupVersion{Group: "example.com", Version: "v1"}
+	for _, tt := range []struct {
+		name    string
+		include []string
+		exclude []string
+		gv      schema.GroupVersion
+		want    bool
+	}{
+		{name: "zero", gv: metrics, want: false},
+		{name: "not included", include: []string{"example.com"}, gv: metrics, want: true},
+		{name: "included", include: []string{"example.com"}, gv: example, want: false},
+		{name: "included by resource", include: []string{"example.com/v1/widgets"}, gv: example, want: false},
+		{name: "other version included", include: []string{"example.com/v2/*"}, gv: example, want: true},
+		{name: "excluded group", exclude: []string{"metrics.k8s.io"}, gv: metrics, want: true},
+		{name: "excluded by default", exclude: defaultExcludeResources, gv: metrics, want: true},
+		{name: "excluded by wildcard", exclude: []string{"*.k8s.io/v1*/*"}, gv: metrics, want: true},
+		{name: "only some resources excluded", exclude: []string{"metrics.k8s.io/v1beta1/pods"}, gv: metrics, want: false},
+	} {
+		include, err := parseResourcePatterns(tt.include)
+		require.NoError(t, err, tt.name)
+		exclude, err := parseResourcePatterns(tt.exclude)
+		require.NoError(t, err, tt.name)
+		f := resourceFilter{include: include, exclude: exclude}
+		assert.Equal(t, tt.want, f.skipsGroupVersion(tt.gv), tt.name)
+	}
+}
+
+func TestAnnotationSelector(t *testing.T) {
+	t.Parallel()
+	annotations := map[string]string{
+		"example.com/owner": "team-a",
+		"example.com/note":  "Contains spaces, and punctuation!",
+	}
+	for _, tt := range []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "", want: true},
+		{selector: "example.com/owner", want: true},
+		{selector: "example.com/missing", want: false},
+		{selector: "!example.com/missing", want: true},
+		{selector: "!example.com/owner", want: false},
+		{selector: "example.com/owner=team-a", want: true},
+		{selector: "example.com/owner=team-b", want: false},
+		{selector: "example.com/owner!=team-b", want: true},
+		{selector: "example.com/owner!=team-a", want: false},
+		{selector: "example.com/missing!=team-a", want: true},
+		{selector: "example.com/owner=team-a,example.com/note", want: true},
+		{selector: "example.com/owner=team-a,!example.com/note", want: false},
+	} {
+		s, err := parseAnnotationSelector(tt.selector)
+		require.NoError(t, err, tt.selector)
+		assert.Equal(t, tt.want, s.match(annotations), tt.selector)
+	}
+
+	for _, selector := range []string{",", "=value", "!", "a,,b"} {
+		_, err := parseAnnotationSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/metadata"
+)
+
+// maxListRestarts is the maximum number of times a list is restarted when its continue token expires.
+const maxListRestarts = 3
+
+// listChunked lists objects in chunks of chunkSize, and calls visit for each object.
+// Pagination is disabled if chunkSize is 0.
+//
+// If the continue token expires with 410 Gone before the list completes, the list is restarted from the beginning.
+// Objects visited before the restart are visited again, so the caller must tolerate duplicates of the objects it keeps.
+// They are not deduplicated here, because that would require remembering every object of the resource.
+func listChunked(ctx context.Context, ri metadata.ResourceInterface, opts metav1.ListOptions, chunkSize int64, visit func(*metav1.PartialObjectMetadata)) error {
+	opts.Limit = chunkSize
+	opts.Continue = ""
+	restarts := 0
+	for {
+		list, err := ri.List(ctx, opts)
+		if apierrors.IsResourceExpired(err) && opts.Continue != "" && restarts < maxListRestarts {
+			restarts++
+			opts.Continue = ""
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			visit(&list.Items[i])
+		}
+		if list.Continue == "" {
+			return nil
+		}
+		opts.Continue = list.Continue
+	}
+}
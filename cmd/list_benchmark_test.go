@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gmeasure"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	benchmarkNamespace  = "benchmark"
+	benchmarkConfigMaps = 200
+	benchmarkDataSize   = 16 * 1024
+)
+
+// allocatedBytes returns the bytes allocated by f.
+func allocatedBytes(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// The benchmark is excluded from `make test` by its label, and run by `make benchmark`.
+var _ = Describe("Benchmark of listing", Serial, Label("benchmark"), func() {
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		ns := &corev1.Namespace{}
+		ns.Name = benchmarkNamespace
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns))).To(Succeed())
+		data := strings.Repeat("x", benchmarkDataSize)
+		for i := 0; i < benchmarkConfigMaps; i++ {
+			cm := &corev1.ConfigMap{}
+			cm.Name = fmt.Sprintf("cm-%d", i)
+			cm.Namespace = benchmarkNamespace
+			cm.Data = map[string]string{"data": data}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+		}
+		DeferCleanup(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace(benchmarkNamespace))).To(Succeed())
+		})
+	})
+
+	It("should allocate less memory with metadata-only listing", func() {
+		resources, err := parseResourcePatterns([]string{"core/configmaps"})
+		Expect(err).NotTo(HaveOccurred())
+		opts := scanOptions{
+			namespaces: namespaceFilter{namespace: benchmarkNamespace, skipClusterScoped: true},
+			resources:  resourceFilter{include: resources},
+		}
+		dynamicClient, err := dynamic.NewForConfig(cfg)
+		Expect(err).NotTo(HaveOccurred())
+
+		experiment := gmeasure.NewExperiment("listing ConfigMaps")
+		AddReportEntry(experiment.Name, experiment)
+		experiment.Sample(func(int) {
+			experiment.RecordValue("full objects", float64(allocatedBytes(func() {
+				list, err := dynamicClient.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace(benchmarkNamespace).List(ctx, metav1.ListOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(list.Items).To(HaveLen(benchmarkConfigMaps))
+			}))/1024, gmeasure.Units("KiB"))
+			experiment.RecordValue("metadata only", float64(allocatedBytes(func() {
+				allResources, err := getAllResources(ctx, cfg, opts)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(allResources).To(HaveLen(benchmarkConfigMaps))
+			}))/1024, gmeasure.Units("KiB"))
+		}, gmeasure.SamplingConfig{N: 5})
+
+		full := experiment.GetStats("full objects").FloatFor(gmeasure.StatMedian)
+		metadataOnly := experiment.GetStats("metadata only").FloatFor(gmeasure.StatMedian)
+		Expect(metadataOnly).To(BeNumerically("<", full/2))
+	})
+})
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+)
+
+// pagedResource serves pages in order, and returns 410 Gone for the continue tokens in expired.
+type pagedResource struct {
+	metadata.ResourceInterface
+	pages    [][]string
+	expired  map[string]int
+	requests []metav1.ListOptions
+}
+
+func (r *pagedResource) List(ctx context.Context, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	r.requests = append(r.requests, opts)
+	page := 0
+	if opts.Continue != "" {
+		if r.expired[opts.Continue] > 0 {
+			r.expired[opts.Continue]--
+			return nil, apierrors.NewResourceExpired("too old resource version")
+		}
+		page = int(opts.Continue[0] - '0')
+	}
+	list := &metav1.PartialObjectMetadataList{}
+	for _, name := range r.pages[page] {
+		list.Items = append(list.Items, metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)}})
+	}
+	if page+1 < len(r.pages) {
+		list.Continue = string(rune('0' + page + 1))
+	}
+	return list, nil
+}
+
+func TestListChunked(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name         string
+		pages        [][]string
+		expired      map[string]int
+		wantNames    []string
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "single page",
+			pages:        [][]string{{"a", "b"}},
+			wantNames:    []string{"a", "b"},
+			wantRequests: 1,
+		},
+		{
+			name:         "multiple pages",
+			pages:        [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+			wantNames:    []string{"a", "b", "c", "d", "e"},
+			wantRequests: 3,
+		},
+		{
+			name:         "restart on expired continue token",
+			pages:        [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+			expired:      map[string]int{"2": 1},
+			wantNames:    []string{"a", "b", "c", "d", "a", "b", "c", "d", "e"},
+			wantRequests: 6,
+		},
+		{
+			name:         "too many restarts",
+			pages:        [][]string{{"a", "b"}, {"c"}},
+			expired:      map[string]int{"1": maxListRestarts + 1},
+			wantNames:    []string{"a", "b", "a", "b", "a", "b", "a", "b"},
+			wantRequests: 2*maxListRestarts + 2,
+			wantErr:      true,
+		},
+	} {
+		ri := &pagedResource{pages: tt.pages, expired: tt.expired}
+		names := make([]string, 0)
+		err := listChunked(context.Background(), ri, metav1.ListOptions{LabelSelector: "app=test", Continue: "ignored"}, 2, func(item *metav1.PartialObjectMetadata) {
+			names = append(names, item.Name)
+		})
+		if tt.wantErr {
+			assert.True(t, apierrors.IsResourceExpired(err), tt.name)
+		} else {
+			require.NoError(t, err, tt.name)
+		}
+		assert.Equal(t, tt.wantNames, names, tt.name)
+		assert.Len(t, ri.requests, tt.wantRequests, tt.name)
+		for _, opts := range ri.requests {
+			assert.Equal(t, int64(2), opts.Limit, tt.name)
+			assert.Equal(t, "app=test", opts.LabelSelector, tt.name)
+		}
+		assert.Equal(t, "", ri.requests[0].Continue, tt.name)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// finalizerManager is a finalizer and the field managers owning it, which are usually the controllers that added it.
+type finalizerManager struct {
+	Finalizer string `json:"finalizer"`
+	// Managers is empty if no entry of managedFields owns the finalizer.
+	Managers []string `json:"managers"`
+}
+
+func (m finalizerManager) String() string {
+	if len(m.Managers) == 0 {
+		return m.Finalizer
+	}
+	return fmt.Sprintf("%s (%s)", m.Finalizer, strings.Join(m.Managers, ","))
+}
+
+// managedFinalizers returns the finalizers owned by an entry of managedFields.
+// Finalizers are a set in the server-side apply schema, so each of them is a key like `v:"example.com/finalizer"` under f:metadata.f:finalizers.
+func managedFinalizers(entry metav1.ManagedFieldsEntry) ([]string, error) {
+	if entry.FieldsV1 == nil {
+		return nil, nil
+	}
+	var fields struct {
+		Metadata struct {
+			Finalizers map[string]json.RawMessage `json:"f:finalizers"`
+		} `json:"f:metadata"`
+	}
+	if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+		return nil, err
+	}
+	finalizers := make([]string, 0, len(fields.Metadata.Finalizers))
+	for key := range fields.Metadata.Finalizers {
+		value, ok := strings.CutPrefix(key, "v:")
+		if !ok {
+			continue
+		}
+		var finalizer string
+		if err := json.Unmarshal([]byte(value), &finalizer); err != nil {
+			return nil, fmt.Errorf("invalid key %q in managedFields: %w", key, err)
+		}
+		finalizers = append(finalizers, finalizer)
+	}
+	return finalizers, nil
+}
+
+// finalizerManagers returns the field managers of each finalizer in the order of finalizers.
+// The API server accepts duplicate finalizers, and they are returned once.
+// Entries of managedFields that cannot be decoded are ignored.
+func finalizerManagers(finalizers []string, managedFields []metav1.ManagedFieldsEntry) []finalizerManager {
+	if len(finalizers) == 0 {
+		return nil
+	}
+	managers := make([]finalizerManager, 0, len(finalizers))
+	for _, f := range finalizers {
+		if slices.ContainsFunc(managers, func(m finalizerManager) bool { return m.Finalizer == f }) {
+			continue
+		}
+		managers = append(managers, finalizerManager{Finalizer: f, Managers: []string{}})
+	}
+	for _, entry := range managedFields {
+		owned, err := managedFinalizers(entry)
+		if err != nil {
+			continue
+		}
+		for i := range managers {
+			if slices.Contains(owned, managers[i].Finalizer) && !slices.Contains(managers[i].Managers, entry.Manager) {
+				managers[i].Managers = append(managers[i].Managers, entry.Manager)
+			}
+		}
+	}
+	return managers
+}
+
+// managers returns the field managers of the finalizers.
+// Managers are unknown if they were not decoded from managedFields when the object was listed.
+func (r resourceMetadata) managers() []finalizerManager {
+	if r.finalizerManagers != nil {
+		return r.finalizerManagers
+	}
+	return finalizerManagers(r.finalizers, nil)
+}
+
+// formatFinalizers returns finalizers with their managers for the table and CSV outputs.
+func formatFinalizers(managers []finalizerManager) string {
+	s := make([]string, 0, len(managers))
+	for _, m := range managers {
+		s = append(s, m.String())
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFinalizerManagers(t *testing.T) {
+	t.Parallel()
+	managedFields := []metav1.ManagedFieldsEntry{
+		{
+			Manager:   "kubectl-create",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{".":{},"f:app":{}}},"f:spec":{}}`)},
+		},
+		{
+			Manager:   "widget-operator",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:finalizers":{".":{},"v:\"example.com/widget\"":{},"v:\"example.com/shared\"":{}}}}`)},
+		},
+		{
+			Manager:   "applier",
+			Operation: metav1.ManagedFieldsOperationApply,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:finalizers":{"v:\"example.com/shared\"":{}}}}`)},
+		},
+		{
+			Manager:     "status-writer",
+			Operation:   metav1.ManagedFieldsOperationUpdate,
+			Subresource: "status",
+			FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)},
+		},
+		{
+			Manager:  "broken",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:finalizers":{"v:not-json":{}}}}`)},
+		},
+		{
+			Manager: "empty",
+		},
+	}
+
+	got := finalizerManagers([]string{"example.com/widget", "example.com/shared", "kubernetes"}, managedFields)
+	assert.Equal(t, []finalizerManager{
+		{Finalizer: "example.com/widget", Managers: []string{"widget-operator"}},
+		{Finalizer: "example.com/shared", Managers: []string{"widget-operator", "applier"}},
+		{Finalizer: "kubernetes", Managers: []string{}},
+	}, got)
+	assert.Equal(t, "example.com/widget (widget-operator),example.com/shared (widget-operator,applier),kubernetes", formatFinalizers(got))
+
+	assert.Nil(t, finalizerManagers(nil, managedFields))
+
+	got = finalizerManagers([]string{"example.com/widget", "kubernetes", "example.com/widget"}, managedFields)
+	assert.Equal(t, []finalizerManager{
+		{Finalizer: "example.com/widget", Managers: []string{"widget-operator"}},
+		{Finalizer: "kubernetes", Managers: []string{}},
+	}, got)
+}
+
+func TestDuplicateFinalizerMetrics(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	zombie := resourceMetadata{
+		uid:               "uid-pod",
+		version:           "v1",
+		kind:              "Pod",
+		name:              "pod",
+		namespace:         "test",
+		deletionTimestamp: &metav1.Time{Time: now.Add(-time.Hour)},
+		finalizers:        []string{"example.com/cleanup", "example.com/cleanup"},
+		rule:              defaultThresholdRule,
+		threshold:         time.Minute,
+	}
+	zombie.finalizerManagers = finalizerManagers(zombie.finalizers, nil)
+	r := newReport([]resourceMetadata{zombie}, nil, "", time.Minute, now, now)
+
+	// Duplicate series would make the registration panic.
+	var families []*dto.MetricFamily
+	require.NotPanics(t, func() {
+		var err error
+		families, err = newReportRegistry(r).Gather()
+		require.NoError(t, err)
+	})
+	for _, mf := range families {
+		if mf.GetName() == "zombie_finalizer_info" {
+			assert.Len(t, mf.GetMetric(), 1)
+		}
+	}
+}
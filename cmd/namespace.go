@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+)
+
+// namespaceDeletionConditions are the conditions set by the namespace controller, in the order of priority to explain a Terminating namespace.
+// Failures prevent the controller from deleting the content, and remaining finalizers are more specific than remaining content.
+var namespaceDeletionConditions = []corev1.NamespaceConditionType{
+	corev1.NamespaceDeletionDiscoveryFailure,
+	corev1.NamespaceDeletionGVParsingFailure,
+	corev1.NamespaceDeletionContentFailure,
+	corev1.NamespaceFinalizersRemaining,
+	corev1.NamespaceContentRemaining,
+}
+
+const (
+	// namespaceReasonFinalizers is the reason when no condition is reported but spec.finalizers are remaining.
+	namespaceReasonFinalizers = "SpecFinalizersRemain"
+	namespaceReasonUnknown    = "Unknown"
+)
+
+// namespaceStatus explains why a Namespace zombie is stuck in Terminating.
+type namespaceStatus struct {
+	// Reason is the reason of the condition that explains the namespace, such as SomeResourcesRemain.
+	Reason string `json:"reason"`
+	// Cause is a human-readable description of the reason.
+	Cause      string                      `json:"cause"`
+	Conditions []corev1.NamespaceCondition `json:"conditions"`
+	// Remaining are the resources still existing in the namespace.
+	Remaining []remainingResource `json:"remaining"`
+	// ListErrors are resources that could not be listed in the namespace. They may be missing in Remaining.
+	ListErrors []listFailure `json:"listErrors,omitempty"`
+}
+
+type remainingResource struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	Count    int    `json:"count"`
+	// Finalizers are the finalizers remaining on the objects of the resource, without duplicates.
+	Finalizers []string `json:"finalizers"`
+}
+
+func (r remainingResource) String() string {
+	s := fmt.Sprintf("%s: %d", schema.GroupResource{Group: r.Group, Resource: r.Resource}, r.Count)
+	if len(r.Finalizers) > 0 {
+		s += fmt.Sprintf(" (%s)", strings.Join(r.Finalizers, ","))
+	}
+	return s
+}
+
+// namespaceCause returns the reason and a human-readable cause of a Terminating namespace from its status.
+func namespaceCause(ns *corev1.Namespace) (string, string) {
+	for _, t := range namespaceDeletionConditions {
+		for _, c := range ns.Status.Conditions {
+			if c.Type == t && c.Status == corev1.ConditionTrue {
+				return c.Reason, c.Message
+			}
+		}
+	}
+	if len(ns.Spec.Finalizers) > 0 {
+		finalizers := make([]string, 0, len(ns.Spec.Finalizers))
+		for _, f := range ns.Spec.Finalizers {
+			finalizers = append(finalizers, string(f))
+		}
+		return namespaceReasonFinalizers, fmt.Sprintf("Finalizers in spec are remaining: %s", strings.Join(finalizers, ", "))
+	}
+	return namespaceReasonUnknown, "No deletion condition is reported by the namespace controller"
+}
+
+// add counts an object of the resource and collects its finalizers.
+func (r *remainingResource) add(item *metav1.PartialObjectMetadata) {
+	r.Count++
+	for _, f := range item.GetFinalizers() {
+		if !slices.Contains(r.Finalizers, f) {
+			r.Finalizers = append(r.Finalizers, f)
+		}
+	}
+}
+
+// listNamespaceContent lists the resources remaining in a namespace, which the namespace controller is to delete.
+// Only the count and the finalizers are needed, so objects are listed as PartialObjectMetadata in chunks of chunkSize.
+// Resources that could not be listed are skipped and returned as failures, so that the others are still reported under restricted RBAC.
+func listNamespaceContent(ctx context.Context, metadataClient metadata.Interface, resLists []*metav1.APIResourceList, namespace string, chunkSize int64) ([]remainingResource, []listFailure, error) {
+	remaining := make([]remainingResource, 0)
+	var failures []listFailure
+	for _, resList := range resLists {
+		gv, err := schema.ParseGroupVersion(resList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resList.APIResources {
+			gvr := gv.WithResource(resource.Name)
+			r := remainingResource{
+				Group:      gvr.Group,
+				Version:    gvr.Version,
+				Resource:   gvr.Resource,
+				Finalizers: []string{},
+			}
+			err := listChunked(ctx, metadataClient.Resource(gvr).Namespace(namespace), metav1.ListOptions{}, chunkSize, r.add)
+			if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+				continue
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, err
+				}
+				fmt.Fprintf(os.Stderr, "Namespace %s: failed to list %s: %v\n", namespace, gvr, err)
+				failures = append(failures, newListFailure(gvr, err))
+				continue
+			}
+			if r.Count > 0 {
+				slices.Sort(r.Finalizers)
+				remaining = append(remaining, r)
+			}
+		}
+	}
+	sortListFailures(failures)
+	return remaining, failures, nil
+}
+
+// diagnoseNamespaces sets the status of Namespace zombies to explain why they are stuck in Terminating.
+// Failures are reported to stderr, because the diagnosis is not essential to the detection.
+func diagnoseNamespaces(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, metadataClient metadata.Interface, chunkSize int64, zombieResources []resourceMetadata) {
+	var resLists []*metav1.APIResourceList
+	gvr := corev1.SchemeGroupVersion.WithResource("namespaces")
+	for i := range zombieResources {
+		res := &zombieResources[i]
+		if res.version != "v1" || res.kind != "Namespace" {
+			continue
+		}
+		obj, err := dynamicClient.Resource(gvr).Get(ctx, res.name, metav1.GetOptions{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Namespace %s: %v\n", res.name, err)
+			continue
+		}
+		ns := &corev1.Namespace{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ns); err != nil {
+			fmt.Fprintf(os.Stderr, "Namespace %s: %v\n", res.name, err)
+			continue
+		}
+		status := &namespaceStatus{Conditions: ns.Status.Conditions}
+		if status.Conditions == nil {
+			status.Conditions = []corev1.NamespaceCondition{}
+		}
+		status.Reason, status.Cause = namespaceCause(ns)
+
+		if resLists == nil {
+			// Discovery failures of some groups are one of the causes to be reported, so resources of other groups are still listed.
+			resLists, err = discoveryClient.ServerPreferredNamespacedResources()
+			if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+				fmt.Fprintf(os.Stderr, "Namespace %s: %v\n", res.name, err)
+				resLists = nil
+			}
+			resLists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "delete"}}, resLists)
+		}
+		status.Remaining, status.ListErrors, err = listNamespaceContent(ctx, metadataClient, resLists, res.name, chunkSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Namespace %s: %v\n", res.name, err)
+			status.Remaining = []remainingResource{}
+		}
+		res.namespaceStatus = status
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNamespaceCause(t *testing.T) {
+	t.Parallel()
+	contentRemaining := corev1.NamespaceCondition{
+		Type:    corev1.NamespaceContentRemaining,
+		Status:  corev1.ConditionTrue,
+		Reason:  "SomeResourcesRemain",
+		Message: "Some resources are remaining: pods. has 2 resource instances",
+	}
+	finalizersRemaining := corev1.NamespaceCondition{
+		Type:    corev1.NamespaceFinalizersRemaining,
+		Status:  corev1.ConditionTrue,
+		Reason:  "SomeFinalizersRemain",
+		Message: "Some content in the namespace has finalizers remaining: example.com/stuck in 2 resource instances",
+	}
+	discoveryFailure := corev1.NamespaceCondition{
+		Type:    corev1.NamespaceDeletionDiscoveryFailure,
+		Status:  corev1.ConditionTrue,
+		Reason:  "DiscoveryFailed",
+		Message: "Discovery failed for some groups, 1 failing: unable to retrieve the complete list of server APIs: metrics.k8s.io/v1beta1: the server is currently unable to handle the request",
+	}
+	resolved := discoveryFailure
+	resolved.Status = corev1.ConditionFalse
+	resolved.Reason = "ResourcesDiscovered"
+
+	for _, tt := range []struct {
+		name       string
+		conditions []corev1.NamespaceCondition
+		finalizers []corev1.FinalizerName
+		wantReason string
+		wantCause  string
+	}{
+		{
+			name:       "content remaining",
+			conditions: []corev1.NamespaceCondition{resolved, contentRemaining},
+			wantReason: "SomeResourcesRemain",
+			wantCause:  contentRemaining.Message,
+		},
+		{
+			name:       "finalizers are more specific than content",
+			conditions: []corev1.NamespaceCondition{contentRemaining, finalizersRemaining},
+			wantReason: "SomeFinalizersRemain",
+			wantCause:  finalizersRemaining.Message,
+		},
+		{
+			name:       "failures have priority",
+			conditions: []corev1.NamespaceCondition{contentRemaining, discoveryFailure},
+			wantReason: "DiscoveryFailed",
+			wantCause:  discoveryFailure.Message,
+		},
+		{
+			name:       "spec finalizers",
+			conditions: []corev1.NamespaceCondition{resolved},
+			finalizers: []corev1.FinalizerName{corev1.FinalizerKubernetes},
+			wantReason: namespaceReasonFinalizers,
+			wantCause:  "Finalizers in spec are remaining: kubernetes",
+		},
+		{
+			name:       "unknown",
+			wantReason: namespaceReasonUnknown,
+			wantCause:  "No deletion condition is reported by the namespace controller",
+		},
+	} {
+		ns := &corev1.Namespace{
+			Spec:   corev1.NamespaceSpec{Finalizers: tt.finalizers},
+			Status: corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating, Conditions: tt.conditions},
+		}
+		reason, cause := namespaceCause(ns)
+		assert.Equal(t, tt.wantReason, reason, tt.name)
+		assert.Equal(t, tt.wantCause, cause, tt.name)
+	}
+}
+
+func newTestObject(apiVersion, kind, namespace, name string, finalizers ...string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  namespace,
+			Name:       name,
+			UID:        types.UID(namespace + "/" + name),
+			Finalizers: finalizers,
+		},
+	}
+}
+
+func TestListNamespaceContent(t *testing.T) {
+	t.Parallel()
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	client := metadatafake.NewSimpleMetadataClient(scheme,
+		newTestObject("v1", "Pod", "terminating", "pod-a", "example.com/b"),
+		newTestObject("v1", "Pod", "terminating", "pod-b", "example.com/a", "example.com/b"),
+		newTestObject("v1", "Pod", "other", "pod-c", "example.com/c"),
+		newTestObject("v1", "ConfigMap", "other", "cm"),
+		newTestObject("example.com/v1", "Widget", "terminating", "widget"),
+	)
+	client.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", errors.New("forbidden"))
+	})
+	resLists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true}, {Name: "secrets", Namespaced: true}, {Name: "configmaps", Namespaced: true}},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: true}},
+		},
+	}
+
+	remaining, failures, err := listNamespaceContent(context.Background(), client, resLists, "terminating", 1)
+	require.NoError(t, err)
+	// A resource that cannot be listed does not hide the others.
+	require.Len(t, failures, 1)
+	assert.Equal(t, "secrets", failures[0].Resource)
+	assert.Equal(t, "Forbidden", failures[0].Reason)
+	assert.Equal(t, []remainingResource{
+		{Version: "v1", Resource: "pods", Count: 2, Finalizers: []string{"example.com/a", "example.com/b"}},
+		{Group: "example.com", Version: "v1", Resource: "widgets", Count: 1, Finalizers: []string{}},
+	}, remaining)
+	assert.Equal(t, "pods: 2 (example.com/a,example.com/b)", remaining[0].String())
+	assert.Equal(t, "widgets.example.com: 1", remaining[1].String())
+}
+
+func TestPrintNamespaceStatusTable(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	zombies := []resourceMetadata{
+		{
+			uid:               "uid-ns",
+			version:           "v1",
+			kind:              "Namespace",
+			name:              "terminating",
+			deletionTimestamp: &metav1.Time{Time: now.Add(-time.Hour)},
+			finalizers:        []string{},
+			rule:              defaultThresholdRule,
+			threshold:         time.Minute,
+			namespaceStatus: &namespaceStatus{
+				Reason:     "SomeFinalizersRemain",
+				Cause:      "Some content in the namespace has finalizers remaining: example.com/b in 2 resource instances",
+				Conditions: []corev1.NamespaceCondition{},
+				Remaining: []remainingResource{
+					{Version: "v1", Resource: "pods", Count: 2, Finalizers: []string{"example.com/b"}},
+					{Group: "example.com", Version: "v1", Resource: "widgets", Count: 1, Finalizers: []string{}},
+				},
+			},
+		},
+	}
+	r := newReport(zombies, nil, "", time.Minute, now, now)
+	assert.Equal(t, "SomeFinalizersRemain", r.Zombies[0].reason())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, printReportTable(buf, r))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 7)
+	assert.Equal(t, "Terminating namespaces:", lines[3])
+	assert.Equal(t, []string{"NAME", "REASON", "CAUSE", "REMAINING"}, strings.Fields(lines[4]))
+	assert.Regexp(t, `^\s*terminating\s+SomeFinalizersRemain\s+Some content .* instances\s+pods: 2 \(example.com/b\)\s*$`, lines[5])
+	assert.Regexp(t, `^\s+widgets.example.com: 1\s*$`, lines[6])
+}
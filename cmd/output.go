@@ -0,0 +1,411 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable  = "table"
+	outputJSON   = "json"
+	outputYAML   = "yaml"
+	outputCSV    = "csv"
+	outputNDJSON = "ndjson"
+
+	// These formats take an argument like "jsonpath={.zombies[*].name}".
+	outputGoTemplate    = "go-template"
+	outputJSONPath      = "jsonpath"
+	outputCustomColumns = "custom-columns"
+)
+
+type reportPrinter func(io.Writer, *report) error
+
+var reportPrinters = map[string]reportPrinter{
+	outputTable:  printReportTable,
+	outputJSON:   printReportJSON,
+	outputYAML:   printReportYAML,
+	outputCSV:    printReportCSV,
+	outputNDJSON: printReportNDJSON,
+}
+
+// newReportPrinter returns the printer for the value of the --output flag.
+func newReportPrinter(output string) (reportPrinter, error) {
+	if printer, ok := reportPrinters[output]; ok {
+		return printer, nil
+	}
+	format, arg, found := strings.Cut(output, "=")
+	if found {
+		switch format {
+		case outputGoTemplate:
+			return newGoTemplatePrinter(arg)
+		case outputJSONPath:
+			return newJSONPathPrinter(arg)
+		case outputCustomColumns:
+			return newCustomColumnsPrinter(arg)
+		}
+	}
+	return nil, fmt.Errorf("unsupported output format: %q", output)
+}
+
+func newTable(w io.Writer) *tablewriter.Table {
+	return tablewriter.NewTable(w,
+		tablewriter.WithRenderer(renderer.NewBlueprint(tw.Rendition{
+			Borders: tw.BorderNone,
+			Settings: tw.Settings{
+				Separators: tw.SeparatorsNone,
+				Lines:      tw.LinesNone,
+			},
+		})),
+		tablewriter.WithConfig(tablewriter.Config{
+			Header: tw.CellConfig{
+				Formatting: tw.CellFormatting{Alignment: tw.AlignLeft},
+			},
+			Row: tw.CellConfig{
+				Formatting: tw.CellFormatting{Alignment: tw.AlignLeft},
+			},
+		}),
+	)
+}
+
+func printReportTable(w io.Writer, r *report) error {
+	zombies := make(map[string]zombieEntry, len(r.Zombies))
+	for _, z := range r.Zombies {
+		zombies[z.UID] = z
+	}
+	treeOf := make(map[string]int)
+	for i, tree := range r.Trees {
+		for _, node := range tree.Nodes {
+			treeOf[node.UID] = i
+		}
+	}
+
+	// Zombies in a tree are printed together with the other objects in the tree, in place of the first one of them.
+	data := make([][]string, 0, len(r.Zombies))
+	printed := make(map[int]bool)
+	for _, z := range r.Zombies {
+		i, ok := treeOf[z.UID]
+		if !ok {
+			data = append(data, []string{z.APIVersion, z.Kind, z.Name, z.Namespace, z.DeletionTimestamp.String(), z.Rule, formatFinalizers(z.FinalizerManagers)})
+			continue
+		}
+		if printed[i] {
+			continue
+		}
+		printed[i] = true
+		for _, node := range r.Trees[i].Nodes {
+			name := node.Name
+			if node.Depth > 0 {
+				name = strings.Repeat("   ", node.Depth-1) + "└─ " + name
+			}
+			if node.Blocking {
+				name += " (blocking)"
+			}
+			rule := "-"
+			if node.Zombie {
+				rule = zombies[node.UID].Rule
+			}
+			data = append(data, []string{node.APIVersion, node.Kind, name, node.Namespace, node.DeletionTimestamp.String(), rule, formatFinalizers(node.FinalizerManagers)})
+		}
+	}
+	table := newTable(w)
+	table.Header("Version", "Kind", "Name", "Namespace", "Timestamp", "Rule", "Finalizers")
+	if err := table.Bulk(data); err != nil {
+		return err
+	}
+	if err := table.Render(); err != nil {
+		return err
+	}
+	if err := printNamespaceStatusTable(w, r.Zombies); err != nil {
+		return err
+	}
+	if err := printSuppressedTable(w, r.Suppressed); err != nil {
+		return err
+	}
+	if err := printDiscoveryFailureTable(w, r.Scan.DiscoveryFailures); err != nil {
+		return err
+	}
+	return printScanErrorTable(w, r.ScanErrors)
+}
+
+func printSuppressedTable(w io.Writer, entries []zombieEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	data := make([][]string, 0, len(entries))
+	for _, z := range entries {
+		data = append(data, []string{z.APIVersion, z.Kind, z.Name, z.Namespace, z.DeletionTimestamp.String(), z.Rule, z.SuppressedBy, formatFinalizers(z.FinalizerManagers)})
+	}
+	if _, err := fmt.Fprintln(w, "\nSuppressed:"); err != nil {
+		return err
+	}
+	table := newTable(w)
+	table.Header("Version", "Kind", "Name", "Namespace", "Timestamp", "Rule", "Suppressed By", "Finalizers")
+	if err := table.Bulk(data); err != nil {
+		return err
+	}
+	return table.Render()
+}
+
+// printDiscoveryFailureTable prints API group versions that were not scanned.
+func printDiscoveryFailureTable(w io.Writer, failures []discoveryFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	data := make([][]string, 0, len(failures))
+	for _, f := range failures {
+		data = append(data, []string{f.GroupVersion, f.Error})
+	}
+	if _, err := fmt.Fprintln(w, "\nDiscovery failures:"); err != nil {
+		return err
+	}
+	table := newTable(w)
+	table.Header("Group Version", "Error")
+	if err := table.Bulk(data); err != nil {
+		return err
+	}
+	return table.Render()
+}
+
+// printNamespaceStatusTable prints why Namespace zombies are stuck in Terminating.
+func printNamespaceStatusTable(w io.Writer, entries []zombieEntry) error {
+	data := make([][]string, 0)
+	for _, z := range entries {
+		if z.NamespaceStatus == nil {
+			continue
+		}
+		remaining := make([]string, 0, len(z.NamespaceStatus.Remaining))
+		for _, res := range z.NamespaceStatus.Remaining {
+			remaining = append(remaining, res.String())
+		}
+		data = append(data, []string{z.Name, z.NamespaceStatus.Reason, z.NamespaceStatus.Cause, strings.Join(remaining, "\n")})
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "\nTerminating namespaces:"); err != nil {
+		return err
+	}
+	table := newTable(w)
+	table.Header("Name", "Reason", "Cause", "Remaining")
+	if err := table.Bulk(data); err != nil {
+		return err
+	}
+	return table.Render()
+}
+
+// printScanErrorTable prints resources that could not be listed.
+func printScanErrorTable(w io.Writer, failures []listFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	data := make([][]string, 0, len(failures))
+	for _, f := range failures {
+		data = append(data, []string{schema.GroupResource{Group: f.Group, Resource: f.Resource}.String(), f.Version, f.Reason, f.Error})
+	}
+	if _, err := fmt.Fprintln(w, "\nScan errors:"); err != nil {
+		return err
+	}
+	table := newTable(w)
+	table.Header("Resource", "Version", "Reason", "Error")
+	if err := table.Bulk(data); err != nil {
+		return err
+	}
+	return table.Render()
+}
+
+func printReportJSON(w io.Writer, r *report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func printReportYAML(w io.Writer, r *report) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func printReportCSV(w io.Writer, r *report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"apiVersion", "kind", "name", "namespace", "deletionTimestamp", "ageSeconds", "finalizers", "finalizerManagers", "root", "reason", "rule", "thresholdSeconds", "suppressedBy"}); err != nil {
+		return err
+	}
+	for _, z := range slices.Concat(r.Zombies, r.Suppressed) {
+		root := ""
+		if z.Root != nil {
+			root = z.Root.String()
+		}
+		record := []string{
+			z.APIVersion,
+			z.Kind,
+			z.Name,
+			z.Namespace,
+			z.DeletionTimestamp.Format(time.RFC3339),
+			strconv.FormatFloat(z.AgeSeconds, 'f', -1, 64),
+			strings.Join(z.Finalizers, ","),
+			formatFinalizers(z.FinalizerManagers),
+			root,
+			z.reason(),
+			z.Rule,
+			strconv.FormatFloat(z.ThresholdSeconds, 'f', -1, 64),
+			z.SuppressedBy,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// printReportNDJSON prints one zombie per line so that the output can be streamed into log pipelines.
+// Suppressed zombies are distinguished by the suppressedBy field.
+func printReportNDJSON(w io.Writer, r *report) error {
+	enc := json.NewEncoder(w)
+	for _, z := range slices.Concat(r.Zombies, r.Suppressed) {
+		if err := enc.Encode(z); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toUnstructured converts v into the generic form used by encoding/json so that
+// templates and JSONPath expressions can refer to fields by their JSON names.
+func toUnstructured(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj any
+	if err := utiljson.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func newGoTemplatePrinter(text string) (reportPrinter, error) {
+	if text == "" {
+		return nil, fmt.Errorf("template for %s must not be empty", outputGoTemplate)
+	}
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return func(w io.Writer, r *report) error {
+		obj, err := toUnstructured(r)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(w, obj)
+	}, nil
+}
+
+// relaxedJSONPath accepts expressions without surrounding braces like kubectl does.
+func relaxedJSONPath(expr string) string {
+	if strings.HasPrefix(expr, "{") {
+		return expr
+	}
+	if !strings.HasPrefix(expr, ".") {
+		expr = "." + expr
+	}
+	return "{" + expr + "}"
+}
+
+func newJSONPathPrinter(expr string) (reportPrinter, error) {
+	if expr == "" {
+		return nil, fmt.Errorf("expression for %s must not be empty", outputJSONPath)
+	}
+	j := jsonpath.New("output").AllowMissingKeys(true)
+	if err := j.Parse(relaxedJSONPath(expr)); err != nil {
+		return nil, fmt.Errorf("failed to parse jsonpath expression: %w", err)
+	}
+	return func(w io.Writer, r *report) error {
+		obj, err := toUnstructured(r)
+		if err != nil {
+			return err
+		}
+		return j.Execute(w, obj)
+	}, nil
+}
+
+type customColumn struct {
+	header string
+	path   *jsonpath.JSONPath
+}
+
+// newCustomColumnsPrinter returns a printer that prints a table with one zombie per row.
+// spec is a comma-separated list of HEADER:JSONPATH evaluated against each zombie, e.g. "NAME:.name,NAMESPACE:.namespace".
+func newCustomColumnsPrinter(spec string) (reportPrinter, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("columns for %s must not be empty", outputCustomColumns)
+	}
+	columns := make([]customColumn, 0)
+	for _, col := range strings.Split(spec, ",") {
+		header, expr, found := strings.Cut(col, ":")
+		if !found || header == "" || expr == "" {
+			return nil, fmt.Errorf("invalid custom column %q, expected HEADER:JSONPATH", col)
+		}
+		j := jsonpath.New(header).AllowMissingKeys(true)
+		if err := j.Parse(relaxedJSONPath(expr)); err != nil {
+			return nil, fmt.Errorf("failed to parse jsonpath expression of column %q: %w", header, err)
+		}
+		columns = append(columns, customColumn{header: header, path: j})
+	}
+	return func(w io.Writer, r *report) error {
+		headers := make([]any, 0, len(columns))
+		for _, col := range columns {
+			headers = append(headers, col.header)
+		}
+		data := make([][]string, 0, len(r.Zombies))
+		for _, z := range r.Zombies {
+			obj, err := toUnstructured(z)
+			if err != nil {
+				return err
+			}
+			row := make([]string, 0, len(columns))
+			for _, col := range columns {
+				results, err := col.path.FindResults(obj)
+				if err != nil {
+					return err
+				}
+				values := make([]string, 0)
+				for _, result := range results {
+					for _, v := range result {
+						values = append(values, fmt.Sprint(v.Interface()))
+					}
+				}
+				if len(values) == 0 {
+					row = append(row, "<none>")
+					continue
+				}
+				row = append(row, strings.Join(values, ","))
+			}
+			data = append(data, row)
+		}
+		table := newTable(w)
+		table.Header(headers...)
+		if err := table.Bulk(data); err != nil {
+			return err
+		}
+		return table.Render()
+	}, nil
+}
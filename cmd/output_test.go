@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+func newTestReport() *report {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(10 * time.Second)
+	zombies := []resourceMetadata{
+		{
+			uid:               "uid-pod-b",
+			version:           "v1",
+			kind:              "Pod",
+			name:              "pod-b",
+			namespace:         "test",
+			deletionTimestamp: &metav1.Time{Time: end.Add(-2 * time.Hour)},
+			rule:              "pods",
+			threshold:         10 * time.Minute,
+		},
+		{
+			uid:               "uid-deploy-a",
+			version:           "apps/v1",
+			kind:              "Deployment",
+			name:              "deploy-a",
+			namespace:         "test",
+			deletionTimestamp: &metav1.Time{Time: end.Add(-3 * time.Hour)},
+			finalizers:        []string{"foregroundDeletion"},
+			rule:              defaultThresholdRule,
+			threshold:         time.Hour,
+		},
+		{
+			uid:               "uid-pod-a",
+			version:           "v1",
+			kind:              "Pod",
+			name:              "pod-a",
+			namespace:         "test",
+			ownerReferences:   []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "deploy-a-rs", UID: "uid-rs-a", Controller: ptr.To(true)}},
+			deletionTimestamp: &metav1.Time{Time: end.Add(-1 * time.Hour)},
+			finalizers:        []string{"example.com/cleanup", "kubernetes"},
+			finalizerManagers: []finalizerManager{
+				{Finalizer: "example.com/cleanup", Managers: []string{"cleanup-operator"}},
+				{Finalizer: "kubernetes", Managers: []string{}},
+			},
+			rule:      "pods",
+			threshold: 10 * time.Minute,
+		},
+	}
+	suppressed := []resourceMetadata{
+		{
+			uid:               "uid-pod-c",
+			version:           "v1",
+			kind:              "Pod",
+			name:              "pod-c",
+			namespace:         "test",
+			deletionTimestamp: &metav1.Time{Time: end.Add(-4 * time.Hour)},
+			rule:              "pods",
+			threshold:         10 * time.Minute,
+			suppressedBy:      ignoreAnnotation,
+		},
+	}
+	replicaSet := resourceMetadata{
+		uid:               "uid-rs-a",
+		version:           "apps/v1",
+		kind:              "ReplicaSet",
+		name:              "deploy-a-rs",
+		namespace:         "test",
+		ownerReferences:   []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deploy-a", UID: "uid-deploy-a", Controller: ptr.To(true), BlockOwnerDeletion: ptr.To(true)}},
+		finalizers:        []string{"example.com/stuck"},
+		deletionTimestamp: &metav1.Time{Time: end.Add(-5 * time.Minute)},
+	}
+	r := newReport(zombies, suppressed, "https://example.com:6443", time.Hour, start, end)
+	r.addTrees(buildZombieTrees(slices.Concat(zombies, suppressed, []resourceMetadata{replicaSet}), map[types.UID]bool{
+		"uid-pod-b":    true,
+		"uid-deploy-a": true,
+		"uid-pod-a":    true,
+	}))
+	return r
+}
+
+func TestPrintReportJSON(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	err := printReportJSON(buf, newTestReport())
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, reportAPIVersion, got["apiVersion"])
+	assert.Equal(t, reportKind, got["kind"])
+	assert.Equal(t, map[string]any{
+		"cluster":           "https://example.com:6443",
+		"thresholdSeconds":  float64(3600),
+		"startTime":         "2024-01-02T03:04:05Z",
+		"endTime":           "2024-01-02T03:04:15Z",
+		"discoveryFailures": []any{},
+	}, got["scan"])
+
+	entries := got["zombies"].([]any)
+	require.Len(t, entries, 3)
+	assert.Equal(t, map[string]any{
+		"apiVersion":        "apps/v1",
+		"kind":              "Deployment",
+		"name":              "deploy-a",
+		"namespace":         "test",
+		"deletionTimestamp": "2024-01-02T00:04:15Z",
+		"uid":               "uid-deploy-a",
+		"ageSeconds":        float64(3 * 60 * 60),
+		"finalizers":        []any{"foregroundDeletion"},
+		"finalizerManagers": []any{map[string]any{"finalizer": "foregroundDeletion", "managers": []any{}}},
+		"propagationPolicy": "Foreground",
+		"rule":              "default",
+		"thresholdSeconds":  float64(3600),
+	}, entries[0])
+	assert.Equal(t, "pod-a", entries[1].(map[string]any)["name"])
+	assert.Equal(t, "pod-b", entries[2].(map[string]any)["name"])
+
+	suppressed := got["suppressed"].([]any)
+	require.Len(t, suppressed, 1)
+	assert.Equal(t, "pod-c", suppressed[0].(map[string]any)["name"])
+	assert.Equal(t, ignoreAnnotation, suppressed[0].(map[string]any)["suppressedBy"])
+	assert.NotContains(t, entries[0], "suppressedBy")
+}
+
+func TestPrintReportJSONEmpty(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	buf := &bytes.Buffer{}
+	err := printReportJSON(buf, newReport(nil, nil, "", time.Hour, now, now))
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, []any{}, got["zombies"])
+	assert.Equal(t, []any{}, got["suppressed"])
+}
+
+func TestPrintReportYAML(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	err := printReportYAML(buf, newTestReport())
+	require.NoError(t, err)
+
+	got := &report{}
+	require.NoError(t, yaml.UnmarshalStrict(buf.Bytes(), got))
+	assert.Equal(t, newTestReport(), got)
+}
+
+func TestPrintReportCSV(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	err := printReportCSV(buf, newTestReport())
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	header := records[0]
+	column := func(record []string, name string) string {
+		i := slices.Index(header, name)
+		require.NotEqual(t, -1, i, name)
+		return record[i]
+	}
+	assert.Equal(t, []string{"apps/v1", "Deployment", "deploy-a", "test", "2024-01-02T00:04:15Z", "10800"}, records[1][:6])
+	assert.Equal(t, "foregroundDeletion", column(records[1], "finalizers"))
+	assert.Equal(t, "default", column(records[1], "rule"))
+	assert.Equal(t, "3600", column(records[1], "thresholdSeconds"))
+	assert.Equal(t, "", column(records[1], "suppressedBy"))
+	assert.Equal(t, "example.com/cleanup,kubernetes", column(records[2], "finalizers"))
+	assert.Equal(t, "example.com/cleanup (cleanup-operator),kubernetes", column(records[2], "finalizerManagers"))
+	assert.Equal(t, "pod-b", column(records[3], "name"))
+	assert.Equal(t, "pod-c", column(records[4], "name"))
+	assert.Equal(t, ignoreAnnotation, column(records[4], "suppressedBy"))
+}
+
+func TestPrintReportNDJSON(t *testing.T) {
+	t.Parallel()
+	r := newTestReport()
+	buf := &bytes.Buffer{}
+	err := printReportNDJSON(buf, r)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := slices.Concat(r.Zombies, r.Suppressed)
+	require.Len(t, lines, len(want))
+	for i, line := range lines {
+		var got zombieEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &got))
+		assert.Equal(t, want[i], got)
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		format string
+		want   string
+	}{
+		{
+			format: `go-template={{range .zombies}}{{.namespace}}/{{.name}}{{"\n"}}{{end}}`,
+			want: `test/deploy-a
+test/pod-a
+test/pod-b
+`,
+		},
+		{
+			format: `go-template={{.scan.thresholdSeconds}} {{len .zombies}}`,
+			want:   `3600 3`,
+		},
+		{
+			format: `jsonpath={range .zombies[?(@.kind=="Pod")]}{.namespace}/{.name}{"\n"}{end}`,
+			want: `test/pod-a
+test/pod-b
+`,
+		},
+		{
+			format: `jsonpath=.zombies[*].ageSeconds`,
+			want:   `10800 3600 7200`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+			printer, err := newReportPrinter(tt.format)
+			require.NoError(t, err)
+			buf := &bytes.Buffer{}
+			err = printer(buf, newTestReport())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestPrintReportTable(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	err := printReportTable(buf, newTestReport())
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 9)
+	assert.Equal(t, []string{"VERSION", "KIND", "NAME", "NAMESPACE", "TIMESTAMP", "RULE", "FINALIZERS"}, strings.Fields(lines[0]))
+	fields := strings.Fields(lines[1])
+	assert.Equal(t, []string{"apps/v1", "Deployment", "deploy-a", "test"}, fields[:4])
+	assert.Equal(t, []string{"default", "foregroundDeletion"}, fields[len(fields)-2:])
+
+	assert.Regexp(t, `^\s*apps/v1\s+ReplicaSet\s+└─ deploy-a-rs \(blocking\)\s+test\s+.*\s-\s+example.com/stuck\s*$`, lines[2])
+	assert.Regexp(t, `^\s*v1\s+Pod\s+   └─ pod-a\s+test\s+.*\spods\s+example.com/cleanup \(cleanup-operator\),kubernetes\s*$`, lines[3])
+	assert.Equal(t, []string{"v1", "Pod", "pod-b", "test"}, strings.Fields(lines[4])[:4])
+
+	assert.Equal(t, "Suppressed:", lines[6])
+	assert.Equal(t, []string{"VERSION", "KIND", "NAME", "NAMESPACE", "TIMESTAMP", "RULE", "SUPPRESSED", "BY", "FINALIZERS"}, strings.Fields(lines[7]))
+	fields = strings.Fields(lines[8])
+	assert.Equal(t, []string{"v1", "Pod", "pod-c", "test"}, fields[:4])
+	assert.Equal(t, []string{"pods", ignoreAnnotation}, fields[len(fields)-2:])
+}
+
+func TestCustomColumnsPrinter(t *testing.T) {
+	t.Parallel()
+	printer, err := newReportPrinter("custom-columns=KIND:.kind,NAME:.name,AGE:.ageSeconds,MISSING:.foo")
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	err = printer(buf, newTestReport())
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"KIND", "NAME", "AGE", "MISSING"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"Deployment", "deploy-a", "10800", "<none>"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"Pod", "pod-a", "3600", "<none>"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"Pod", "pod-b", "7200", "<none>"}, strings.Fields(lines[3]))
+}
+
+func TestNewReportPrinterError(t *testing.T) {
+	t.Parallel()
+	for _, output := range []string{
+		"xml",
+		"go-template",
+		"go-template=",
+		"go-template={{.zombies",
+		"jsonpath={.zombies[}",
+		"custom-columns=NAME",
+		"custom-columns=NAME:.name,:.namespace",
+	} {
+		_, err := newReportPrinter(output)
+		assert.Error(t, err, output)
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type objectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	UID        string `json:"uid"`
+}
+
+func (r objectReference) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+func newObjectReference(res resourceMetadata) objectReference {
+	return objectReference{
+		APIVersion: res.version,
+		Kind:       res.kind,
+		Name:       res.name,
+		Namespace:  res.namespace,
+		UID:        string(res.uid),
+	}
+}
+
+type ownerReference struct {
+	APIVersion         string `json:"apiVersion"`
+	Kind               string `json:"kind"`
+	Name               string `json:"name"`
+	UID                string `json:"uid"`
+	Controller         bool   `json:"controller,omitempty"`
+	BlockOwnerDeletion bool   `json:"blockOwnerDeletion,omitempty"`
+}
+
+func newOwnerReferences(refs []metav1.OwnerReference) []ownerReference {
+	if len(refs) == 0 {
+		return nil
+	}
+	owners := make([]ownerReference, 0, len(refs))
+	for _, ref := range refs {
+		owners = append(owners, ownerReference{
+			APIVersion:         ref.APIVersion,
+			Kind:               ref.Kind,
+			Name:               ref.Name,
+			UID:                string(ref.UID),
+			Controller:         ref.Controller != nil && *ref.Controller,
+			BlockOwnerDeletion: ref.BlockOwnerDeletion != nil && *ref.BlockOwnerDeletion,
+		})
+	}
+	return owners
+}
+
+// propagationPolicy returns the propagation policy of an object being deleted, which is recorded as its finalizers.
+func propagationPolicy(finalizers []string) string {
+	switch {
+	case slices.Contains(finalizers, metav1.FinalizerDeleteDependents):
+		return string(metav1.DeletePropagationForeground)
+	case slices.Contains(finalizers, metav1.FinalizerOrphanDependents):
+		return string(metav1.DeletePropagationOrphan)
+	}
+	return ""
+}
+
+// zombieTree is a tree of objects being deleted, which are connected by their owner references.
+// The root and descendants are not necessarily zombies, but each tree contains at least one zombie.
+type zombieTree struct {
+	Root objectReference `json:"root"`
+	// Nodes are the objects in the tree in depth-first order, starting with the root.
+	Nodes []zombieTreeNode `json:"nodes"`
+}
+
+type zombieTreeNode struct {
+	objectReference
+	// Parent is the UID of the owner in the tree. It is empty for the root.
+	Parent            string    `json:"parent,omitempty"`
+	Depth             int       `json:"depth"`
+	DeletionTimestamp time.Time `json:"deletionTimestamp"`
+	Finalizers        []string  `json:"finalizers"`
+	// FinalizerManagers are the field managers of each finalizer.
+	FinalizerManagers []finalizerManager `json:"finalizerManagers,omitempty"`
+	Zombie            bool               `json:"zombie"`
+	// Blocking is true if the object blocks the foreground deletion of its parent.
+	Blocking bool `json:"blocking"`
+}
+
+// buildZombieTrees connects objects being deleted by their owner references, and returns
+// the trees that contain zombies and have at least one descendant.
+func buildZombieTrees(deletingResources []resourceMetadata, zombieUIDs map[types.UID]bool) []zombieTree {
+	byUID := make(map[types.UID]resourceMetadata, len(deletingResources))
+	for _, res := range deletingResources {
+		byUID[res.uid] = res
+	}
+
+	// An object owned by multiple objects being deleted is put under its controller, or the first owner.
+	children := make(map[types.UID][]resourceMetadata)
+	blocking := make(map[types.UID]bool)
+	hasParent := make(map[types.UID]bool)
+	for _, res := range deletingResources {
+		var parent *metav1.OwnerReference
+		for i, ref := range res.ownerReferences {
+			if _, ok := byUID[ref.UID]; !ok {
+				continue
+			}
+			if parent == nil || (ref.Controller != nil && *ref.Controller) {
+				parent = &res.ownerReferences[i]
+			}
+		}
+		if parent == nil {
+			continue
+		}
+		children[parent.UID] = append(children[parent.UID], res)
+		hasParent[res.uid] = true
+		owner := byUID[parent.UID]
+		if parent.BlockOwnerDeletion != nil && *parent.BlockOwnerDeletion && propagationPolicy(owner.finalizers) == string(metav1.DeletePropagationForeground) {
+			blocking[res.uid] = true
+		}
+	}
+	for _, c := range children {
+		sortResources(c)
+	}
+
+	roots := make([]resourceMetadata, 0)
+	for _, res := range deletingResources {
+		if !hasParent[res.uid] && len(children[res.uid]) > 0 {
+			roots = append(roots, res)
+		}
+	}
+	sortResources(roots)
+
+	trees := make([]zombieTree, 0)
+	for _, root := range roots {
+		tree := zombieTree{Root: newObjectReference(root)}
+		containsZombie := false
+		var walk func(res resourceMetadata, parent types.UID, depth int)
+		walk = func(res resourceMetadata, parent types.UID, depth int) {
+			finalizers := res.finalizers
+			if finalizers == nil {
+				finalizers = []string{}
+			}
+			tree.Nodes = append(tree.Nodes, zombieTreeNode{
+				objectReference:   newObjectReference(res),
+				Parent:            string(parent),
+				Depth:             depth,
+				DeletionTimestamp: res.deletionTimestamp.UTC(),
+				Finalizers:        finalizers,
+				FinalizerManagers: res.managers(),
+				Zombie:            zombieUIDs[res.uid],
+				Blocking:          blocking[res.uid],
+			})
+			containsZombie = containsZombie || zombieUIDs[res.uid]
+			for _, child := range children[res.uid] {
+				walk(child, res.uid, depth+1)
+			}
+		}
+		walk(root, "", 0)
+		if containsZombie {
+			trees = append(trees, tree)
+		}
+	}
+	return trees
+}
+
+func sortResources(resources []resourceMetadata) {
+	slices.SortFunc(resources, func(a, b resourceMetadata) int {
+		return strings.Compare(newObjectReference(a).String(), newObjectReference(b).String())
+	})
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestPropagationPolicy(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "Foreground", propagationPolicy([]string{"example.com/a", metav1.FinalizerDeleteDependents}))
+	assert.Equal(t, "Orphan", propagationPolicy([]string{metav1.FinalizerOrphanDependents}))
+	assert.Equal(t, "", propagationPolicy([]string{"kubernetes"}))
+	assert.Equal(t, "", propagationPolicy(nil))
+}
+
+func TestBuildZombieTrees(t *testing.T) {
+	t.Parallel()
+	deletedAt := &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	newResource := func(uid, kind, name string, finalizers []string, owners ...metav1.OwnerReference) resourceMetadata {
+		return resourceMetadata{
+			uid:               types.UID(uid),
+			version:           "v1",
+			kind:              kind,
+			name:              name,
+			namespace:         "test",
+			finalizers:        finalizers,
+			ownerReferences:   owners,
+			deletionTimestamp: deletedAt,
+		}
+	}
+	ownedBy := func(uid string, controller, block bool) metav1.OwnerReference {
+		return metav1.OwnerReference{UID: types.UID(uid), Controller: ptr.To(controller), BlockOwnerDeletion: ptr.To(block)}
+	}
+
+	resources := []resourceMetadata{
+		// foreground deletion of a deployment blocked by a pod
+		newResource("deploy", "Deployment", "web", []string{metav1.FinalizerDeleteDependents}),
+		newResource("rs", "ReplicaSet", "web-1", []string{metav1.FinalizerDeleteDependents}, ownedBy("deploy", true, true)),
+		newResource("pod-2", "Pod", "web-1-b", []string{"example.com/stuck"}, ownedBy("rs", true, true)),
+		newResource("pod-1", "Pod", "web-1-a", []string{"example.com/stuck"}, ownedBy("other", false, false), ownedBy("rs", true, true)),
+		// background deletion
+		newResource("owner", "ConfigMap", "owner", []string{"example.com/a"}),
+		newResource("owned", "ConfigMap", "owned", []string{"example.com/b"}, ownedBy("owner", false, true)),
+		// not zombies
+		newResource("young-owner", "ConfigMap", "young-owner", nil),
+		newResource("young-owned", "ConfigMap", "young-owned", nil, ownedBy("young-owner", false, false)),
+		// single zombie
+		newResource("single", "Secret", "single", []string{"kubernetes"}),
+		// cycle
+		newResource("cycle-a", "ConfigMap", "cycle-a", nil, ownedBy("cycle-b", false, false)),
+		newResource("cycle-b", "ConfigMap", "cycle-b", nil, ownedBy("cycle-a", false, false)),
+	}
+	zombieUIDs := map[types.UID]bool{
+		"deploy":  true,
+		"pod-1":   true,
+		"owned":   true,
+		"single":  true,
+		"cycle-a": true,
+	}
+
+	trees := buildZombieTrees(resources, zombieUIDs)
+	require.Len(t, trees, 2)
+
+	assert.Equal(t, "owner", trees[0].Root.UID)
+	require.Len(t, trees[0].Nodes, 2)
+	assert.False(t, trees[0].Nodes[0].Zombie)
+	assert.Equal(t, "owned", trees[0].Nodes[1].UID)
+	assert.Equal(t, "owner", trees[0].Nodes[1].Parent)
+	assert.True(t, trees[0].Nodes[1].Zombie)
+	assert.False(t, trees[0].Nodes[1].Blocking)
+
+	assert.Equal(t, "deploy", trees[1].Root.UID)
+	nodes := trees[1].Nodes
+	require.Len(t, nodes, 4)
+	for i, want := range []struct {
+		uid      string
+		parent   string
+		depth    int
+		zombie   bool
+		blocking bool
+	}{
+		{uid: "deploy", parent: "", depth: 0, zombie: true, blocking: false},
+		{uid: "rs", parent: "deploy", depth: 1, zombie: false, blocking: true},
+		{uid: "pod-1", parent: "rs", depth: 2, zombie: true, blocking: true},
+		{uid: "pod-2", parent: "rs", depth: 2, zombie: false, blocking: true},
+	} {
+		assert.Equal(t, want.uid, nodes[i].UID)
+		assert.Equal(t, want.parent, nodes[i].Parent, want.uid)
+		assert.Equal(t, want.depth, nodes[i].Depth, want.uid)
+		assert.Equal(t, want.zombie, nodes[i].Zombie, want.uid)
+		assert.Equal(t, want.blocking, nodes[i].Blocking, want.uid)
+	}
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// pushgatewayUsernameEnv and pushgatewayPasswordEnv give the basic auth credentials when the flags are not given.
+	pushgatewayUsernameEnv = "ZOMBIE_DETECTOR_PUSHGATEWAY_USERNAME"
+	pushgatewayPasswordEnv = "ZOMBIE_DETECTOR_PUSHGATEWAY_PASSWORD"
+)
+
+var pushgatewayUsernameFlag string
+var pushgatewayPasswordFileFlag string
+var pushgatewayBearerTokenFileFlag string
+var pushgatewayCAFileFlag string
+var pushgatewayCertFileFlag string
+var pushgatewayKeyFileFlag string
+var pushgatewayInsecureSkipVerifyFlag bool
+var pushgatewayHeadersFlag []string
+var pushJobFlag string
+var pushGroupingFlag []string
+
+func init() {
+	rootCmd.Flags().StringVar(&pushgatewayUsernameFlag, "pushgateway-username", "", "username of basic auth for Pushgateway. Defaults to $"+pushgatewayUsernameEnv)
+	rootCmd.Flags().StringVar(&pushgatewayPasswordFileFlag, "pushgateway-password-file", "", "path to the file containing the password of basic auth for Pushgateway. Defaults to $"+pushgatewayPasswordEnv)
+	rootCmd.Flags().StringVar(&pushgatewayBearerTokenFileFlag, "pushgateway-bearer-token-file", "", "path to the file containing the bearer token for Pushgateway")
+	rootCmd.Flags().StringVar(&pushgatewayCAFileFlag, "pushgateway-ca-file", "", "path to the CA bundle to verify the certificate of Pushgateway")
+	rootCmd.Flags().StringVar(&pushgatewayCertFileFlag, "pushgateway-cert-file", "", "path to the client certificate for Pushgateway")
+	rootCmd.Flags().StringVar(&pushgatewayKeyFileFlag, "pushgateway-key-file", "", "path to the private key of the client certificate for Pushgateway")
+	rootCmd.Flags().BoolVar(&pushgatewayInsecureSkipVerifyFlag, "pushgateway-insecure-skip-verify", false, "skip the verification of the certificate of Pushgateway")
+	rootCmd.Flags().StringVar(&pushJobFlag, "push-job", "zombie-detector", "job name of the metrics pushed to Pushgateway")
+	rootCmd.Flags().StringArrayVar(&pushGroupingFlag, "push-grouping", nil, "grouping key in the form of NAME=VALUE of the metrics pushed to Pushgateway, such as cluster=prod-a. Can be repeated")
+	rootCmd.Flags().StringArrayVar(&pushgatewayHeadersFlag, "pushgateway-header", nil, "extra HTTP header in the form of NAME=VALUE sent to Pushgateway. Can be repeated")
+}
+
+// pushgatewayClient pushes metrics to a Pushgateway with the authentication and TLS settings given by flags.
+// Metrics are pushed to the group identified by the job and the grouping keys, and other groups are not modified.
+type pushgatewayClient struct {
+	endpoint string
+	job      string
+	grouping map[string]string
+	client   push.HTTPDoer
+	header   http.Header
+	username string
+	password string
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func newPushgatewayTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: pushgatewayInsecureSkipVerifyFlag}
+	if pushgatewayCAFileFlag != "" {
+		data, err := os.ReadFile(pushgatewayCAFileFlag)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate is found in %s", pushgatewayCAFileFlag)
+		}
+		cfg.RootCAs = pool
+	}
+	if (pushgatewayCertFileFlag == "") != (pushgatewayKeyFileFlag == "") {
+		return nil, errors.New("--pushgateway-cert-file and --pushgateway-key-file must be given together")
+	}
+	if pushgatewayCertFileFlag != "" {
+		cert, err := tls.LoadX509KeyPair(pushgatewayCertFileFlag, pushgatewayKeyFileFlag)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func parseHeaders(headers []string) (http.Header, error) {
+	header := http.Header{}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q: must be in the form of NAME=VALUE", h)
+		}
+		header.Add(name, value)
+	}
+	return header, nil
+}
+
+// parseGroupingKeys parses the grouping keys that identify the group of metrics in Pushgateway in addition to the job.
+func parseGroupingKeys(keys []string) (map[string]string, error) {
+	grouping := make(map[string]string)
+	for _, k := range keys {
+		name, value, ok := strings.Cut(k, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid grouping key %q: must be in the form of NAME=VALUE", k)
+		}
+		if name == "job" {
+			return nil, errors.New("grouping key must not be job. Use --push-job instead")
+		}
+		if _, ok := grouping[name]; ok {
+			return nil, fmt.Errorf("duplicate grouping key %q", name)
+		}
+		grouping[name] = value
+	}
+	return grouping, nil
+}
+
+func newPushgatewayClient(endpoint string) (*pushgatewayClient, error) {
+	if pushJobFlag == "" {
+		return nil, errors.New("--push-job must not be empty")
+	}
+	c := &pushgatewayClient{endpoint: endpoint, job: pushJobFlag}
+	var err error
+	c.grouping, err = parseGroupingKeys(pushGroupingFlag)
+	if err != nil {
+		return nil, err
+	}
+	c.header, err = parseHeaders(pushgatewayHeadersFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	c.username = pushgatewayUsernameFlag
+	if c.username == "" {
+		c.username = os.Getenv(pushgatewayUsernameEnv)
+	}
+	if pushgatewayPasswordFileFlag != "" {
+		c.password, err = readSecretFile(pushgatewayPasswordFileFlag)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c.password = os.Getenv(pushgatewayPasswordEnv)
+	}
+	if pushgatewayBearerTokenFileFlag != "" {
+		if c.username != "" {
+			return nil, errors.New("basic auth and bearer token for Pushgateway must not be given together")
+		}
+		token, err := readSecretFile(pushgatewayBearerTokenFileFlag)
+		if err != nil {
+			return nil, err
+		}
+		c.header.Set("Authorization", "Bearer "+token)
+	}
+
+	tlsConfig, err := newPushgatewayTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.client = &retryClient{client: &http.Client{Transport: transport}, backoff: pushBackoff}
+	return c, nil
+}
+
+// pushBackoff is the backoff of retries of a request to Pushgateway.
+// Steps is the maximum number of attempts.
+var pushBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// retryClient sends requests to Pushgateway, and retries them with exponential backoff on transient errors.
+// A transient error is a failure of the connection, 429 Too Many Requests or a 5xx status.
+type retryClient struct {
+	client  *http.Client
+	backoff wait.Backoff
+}
+
+func isTransientStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+func (c *retryClient) Do(req *http.Request) (*http.Response, error) {
+	backoff := c.backoff
+	for {
+		resp, err := c.client.Do(req)
+		if err == nil && !isTransientStatus(resp.StatusCode) || backoff.Steps <= 1 {
+			return resp, err
+		}
+		if err != nil {
+			log.Printf("request to Pushgateway failed, retrying: %v", err)
+		} else {
+			log.Printf("request to Pushgateway failed with status %d, retrying", resp.StatusCode)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff.Step()):
+		}
+		if req.GetBody != nil {
+			req = req.Clone(req.Context())
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// pusher returns a push.Pusher for the group of the client.
+func (c *pushgatewayClient) pusher() *push.Pusher {
+	p := push.New(c.endpoint, c.job).Client(c.client).Header(c.header)
+	for name, value := range c.grouping {
+		p = p.Grouping(name, value)
+	}
+	if c.username != "" {
+		p = p.BasicAuth(c.username, c.password)
+	}
+	return p
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetPushgatewayFlags restores the flags for Pushgateway after a test.
+func resetPushgatewayFlags(t *testing.T) {
+	t.Cleanup(func() {
+		pushgatewayUsernameFlag = ""
+		pushgatewayPasswordFileFlag = ""
+		pushgatewayBearerTokenFileFlag = ""
+		pushgatewayCAFileFlag = ""
+		pushgatewayCertFileFlag = ""
+		pushgatewayKeyFileFlag = ""
+		pushgatewayInsecureSkipVerifyFlag = false
+		pushgatewayHeadersFlag = nil
+		pushJobFlag = "zombie-detector"
+		pushGroupingFlag = nil
+	})
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+// newClientCertificate returns PEM-encoded self-signed certificate and key for a client.
+func newClientCertificate(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "zombie-detector"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+type recordedRequest struct {
+	method     string
+	path       string
+	header     http.Header
+	body       string
+	clientCert bool
+}
+
+// newRecordingServer returns a server that records requests.
+// The n-th request is responded with statuses[n], or 202 Accepted if statuses has no more elements.
+func newRecordingServer(t *testing.T, statuses ...int) (*httptest.Server, func() []recordedRequest) {
+	var mu sync.Mutex
+	var requests []recordedRequest
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{method: r.Method, path: r.URL.Path, header: r.Header.Clone(), body: string(body), clientCert: len(r.TLS.PeerCertificates) > 0})
+		status := http.StatusAccepted
+		if len(requests) <= len(statuses) {
+			status = statuses[len(requests)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestPushgatewayClient(t *testing.T) {
+	resetPushgatewayFlags(t)
+	server, requests := newRecordingServer(t)
+	caFile := writeFile(t, "ca.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	cert, key := newClientCertificate(t)
+
+	pushgatewayUsernameFlag = "detector"
+	pushgatewayPasswordFileFlag = writeFile(t, "password", []byte("s3cret\n"))
+	pushgatewayCAFileFlag = caFile
+	pushgatewayCertFileFlag = writeFile(t, "tls.crt", cert)
+	pushgatewayKeyFileFlag = writeFile(t, "tls.key", key)
+	pushgatewayHeadersFlag = []string{"X-Scope-OrgID=tenant-a", "X-Query=a=b"}
+
+	client, err := newPushgatewayClient(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, postZombieResourcesMetrics(newTestReport(), client))
+
+	got := requests()
+	require.Len(t, got, 1)
+	assert.Equal(t, http.MethodPut, got[0].method)
+	for _, r := range got {
+		assert.Equal(t, "/metrics/job/zombie-detector", r.path)
+		req := &http.Request{Header: r.header}
+		username, password, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "detector", username)
+		assert.Equal(t, "s3cret", password)
+		assert.Equal(t, "tenant-a", r.header.Get("X-Scope-OrgID"))
+		assert.Equal(t, "a=b", r.header.Get("X-Query"))
+		assert.True(t, r.clientCert)
+	}
+}
+
+func TestPushgatewayClientBearerToken(t *testing.T) {
+	resetPushgatewayFlags(t)
+	server, requests := newRecordingServer(t)
+	pushgatewayBearerTokenFileFlag = writeFile(t, "token", []byte("my-token\n"))
+	pushgatewayInsecureSkipVerifyFlag = true
+	t.Setenv(pushgatewayPasswordEnv, "ignored")
+
+	client, err := newPushgatewayClient(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, client.pusher().Delete())
+
+	got := requests()
+	require.Len(t, got, 1)
+	assert.Equal(t, "Bearer my-token", got[0].header.Get("Authorization"))
+	assert.False(t, got[0].clientCert)
+}
+
+func TestPushgatewayClientGrouping(t *testing.T) {
+	resetPushgatewayFlags(t)
+	server, requests := newRecordingServer(t)
+	pushgatewayInsecureSkipVerifyFlag = true
+	pushJobFlag = "zombies"
+	pushGroupingFlag = []string{"cluster=prod-a", "zone=tokyo/1"}
+
+	client, err := newPushgatewayClient(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, postZombieResourcesMetrics(newTestReport(), client))
+
+	got := requests()
+	require.Len(t, got, 1)
+	for _, r := range got {
+		// The order of the grouping keys in the path is not significant.
+		assert.True(t, strings.HasPrefix(r.path, "/metrics/job/zombies/"), r.path)
+		assert.Contains(t, r.path, "/cluster/prod-a")
+		assert.Contains(t, r.path, "/zone@base64/dG9reW8vMQ")
+	}
+}
+
+func TestPushgatewayClientRetry(t *testing.T) {
+	resetPushgatewayFlags(t)
+	pushgatewayInsecureSkipVerifyFlag = true
+	backoff := pushBackoff
+	t.Cleanup(func() {
+		pushBackoff = backoff
+	})
+	pushBackoff.Duration = time.Millisecond
+	pushBackoff.Steps = 3
+
+	for _, tt := range []struct {
+		name      string
+		statuses  []int
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "recover from transient errors",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			wantCalls: 3,
+		},
+		{
+			name:      "give up after the maximum attempts",
+			statuses:  []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "no retry on a client error",
+			statuses:  []int{http.StatusBadRequest},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newRecordingServer(t, tt.statuses...)
+			client, err := newPushgatewayClient(server.URL)
+			require.NoError(t, err)
+
+			err = postZombieResourcesMetrics(newTestReport(), client)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			got := requests()
+			require.Len(t, got, tt.wantCalls)
+			for _, r := range got {
+				assert.Equal(t, http.MethodPut, r.method)
+				// The body is sent again on every attempt.
+				assert.Contains(t, r.body, "zombie_detector_last_success_timestamp_seconds")
+			}
+		})
+	}
+}
+
+func TestPushgatewayClientBasicAuthFromEnv(t *testing.T) {
+	resetPushgatewayFlags(t)
+	t.Setenv(pushgatewayUsernameEnv, "env-user")
+	t.Setenv(pushgatewayPasswordEnv, "env-password")
+	client, err := newPushgatewayClient("http://localhost:9091")
+	require.NoError(t, err)
+	assert.Equal(t, "env-user", client.username)
+	assert.Equal(t, "env-password", client.password)
+}
+
+func TestPushgatewayClientError(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		setup func(t *testing.T)
+	}{
+		{
+			name: "cert without key",
+			setup: func(t *testing.T) {
+				pushgatewayCertFileFlag = "tls.crt"
+			},
+		},
+		{
+			name: "invalid header",
+			setup: func(t *testing.T) {
+				pushgatewayHeadersFlag = []string{"X-Scope-OrgID"}
+			},
+		},
+		{
+			name: "invalid grouping key",
+			setup: func(t *testing.T) {
+				pushGroupingFlag = []string{"cluster"}
+			},
+		},
+		{
+			name: "job as grouping key",
+			setup: func(t *testing.T) {
+				pushGroupingFlag = []string{"job=other"}
+			},
+		},
+		{
+			name: "duplicate grouping key",
+			setup: func(t *testing.T) {
+				pushGroupingFlag = []string{"cluster=a", "cluster=b"}
+			},
+		},
+		{
+			name: "empty job",
+			setup: func(t *testing.T) {
+				pushJobFlag = ""
+			},
+		},
+		{
+			name: "basic auth and bearer token",
+			setup: func(t *testing.T) {
+				pushgatewayUsernameFlag = "detector"
+				pushgatewayBearerTokenFileFlag = writeFile(t, "token", []byte("my-token"))
+			},
+		},
+		{
+			name: "missing password file",
+			setup: func(t *testing.T) {
+				pushgatewayPasswordFileFlag = filepath.Join(t.TempDir(), "missing")
+			},
+		},
+		{
+			name: "invalid CA bundle",
+			setup: func(t *testing.T) {
+				pushgatewayCAFileFlag = writeFile(t, "ca.crt", []byte("not a certificate"))
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resetPushgatewayFlags(t)
+			tt.setup(t)
+			_, err := newPushgatewayClient("http://localhost:9091")
+			assert.Error(t, err)
+		})
+	}
+}
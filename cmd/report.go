@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	reportAPIVersion = "zombie-detector.cybozu.io/v1"
+	reportKind       = "ZombieReport"
+)
+
+// report is the machine-readable result of a scan.
+// Fields must not be renamed or removed without bumping reportAPIVersion.
+type report struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Scan       scanMetadata  `json:"scan"`
+	Zombies    []zombieEntry `json:"zombies"`
+	// Suppressed are zombies suppressed by annotations. They are reported but not alerted.
+	Suppressed []zombieEntry `json:"suppressed"`
+	// Trees group zombies and other objects being deleted by their owner references.
+	Trees []zombieTree `json:"trees"`
+	// ScanErrors are resources that could not be listed. Zombies of them may be missing in the report.
+	ScanErrors []listFailure `json:"scanErrors"`
+
+	// objectsScanned is the number of objects listed for each resource. It is exposed only as metrics.
+	objectsScanned map[schema.GroupResource]int
+}
+
+type scanMetadata struct {
+	Cluster          string    `json:"cluster"`
+	ThresholdSeconds float64   `json:"thresholdSeconds"`
+	StartTime        time.Time `json:"startTime"`
+	EndTime          time.Time `json:"endTime"`
+	// DiscoveryFailures are API group versions that could not be discovered and were not scanned.
+	DiscoveryFailures []discoveryFailure `json:"discoveryFailures"`
+}
+
+type zombieEntry struct {
+	UID               string    `json:"uid"`
+	APIVersion        string    `json:"apiVersion"`
+	Kind              string    `json:"kind"`
+	Name              string    `json:"name"`
+	Namespace         string    `json:"namespace,omitempty"`
+	DeletionTimestamp time.Time `json:"deletionTimestamp"`
+	AgeSeconds        float64   `json:"ageSeconds"`
+	Finalizers        []string  `json:"finalizers"`
+	// FinalizerManagers are the field managers of each finalizer.
+	FinalizerManagers []finalizerManager `json:"finalizerManagers,omitempty"`
+	// PropagationPolicy is "Foreground" or "Orphan" if the zombie is deleted with the policy.
+	PropagationPolicy string           `json:"propagationPolicy,omitempty"`
+	OwnerReferences   []ownerReference `json:"ownerReferences,omitempty"`
+	// NamespaceStatus explains why a Namespace zombie is stuck in Terminating.
+	NamespaceStatus *namespaceStatus `json:"namespaceStatus,omitempty"`
+	// Root is the root of the tree in Trees that contains the zombie as a descendant.
+	Root *objectReference `json:"root,omitempty"`
+	// Rule is the name of the threshold rule applied to the zombie.
+	Rule             string  `json:"rule"`
+	ThresholdSeconds float64 `json:"thresholdSeconds"`
+	// SuppressedBy is the annotation that suppresses the zombie. It is set only for entries in report.Suppressed.
+	SuppressedBy string `json:"suppressedBy,omitempty"`
+}
+
+// reason returns the reason why a Namespace zombie is stuck, or an empty string for other zombies.
+func (z zombieEntry) reason() string {
+	if z.NamespaceStatus == nil {
+		return ""
+	}
+	return z.NamespaceStatus.Reason
+}
+
+func newZombieEntries(resources []resourceMetadata, endTime time.Time) []zombieEntry {
+	entries := make([]zombieEntry, 0, len(resources))
+	for _, res := range resources {
+		finalizers := res.finalizers
+		if finalizers == nil {
+			finalizers = []string{}
+		}
+		entries = append(entries, zombieEntry{
+			UID:               string(res.uid),
+			APIVersion:        res.version,
+			Kind:              res.kind,
+			Name:              res.name,
+			Namespace:         res.namespace,
+			DeletionTimestamp: res.deletionTimestamp.UTC(),
+			AgeSeconds:        endTime.Sub(res.deletionTimestamp.Time).Truncate(time.Second).Seconds(),
+			Finalizers:        finalizers,
+			FinalizerManagers: res.managers(),
+			PropagationPolicy: propagationPolicy(res.finalizers),
+			OwnerReferences:   newOwnerReferences(res.ownerReferences),
+			NamespaceStatus:   res.namespaceStatus,
+			Rule:              res.rule,
+			ThresholdSeconds:  res.threshold.Seconds(),
+			SuppressedBy:      res.suppressedBy,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.APIVersion != b.APIVersion {
+			return a.APIVersion < b.APIVersion
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return entries
+}
+
+func newReport(zombieResources, suppressedResources []resourceMetadata, cluster string, threshold time.Duration, startTime, endTime time.Time) *report {
+	return &report{
+		APIVersion: reportAPIVersion,
+		Kind:       reportKind,
+		Scan: scanMetadata{
+			Cluster:           cluster,
+			ThresholdSeconds:  threshold.Seconds(),
+			StartTime:         startTime.UTC(),
+			EndTime:           endTime.UTC(),
+			DiscoveryFailures: []discoveryFailure{},
+		},
+		Zombies:    newZombieEntries(zombieResources, endTime),
+		Suppressed: newZombieEntries(suppressedResources, endTime),
+		Trees:      []zombieTree{},
+		ScanErrors: []listFailure{},
+	}
+}
+
+// addTrees adds trees to the report and sets the root of zombies in them.
+func (r *report) addTrees(trees []zombieTree) {
+	r.Trees = append(r.Trees, trees...)
+	roots := make(map[string]objectReference)
+	for _, tree := range trees {
+		for _, node := range tree.Nodes[1:] {
+			roots[node.UID] = tree.Root
+		}
+	}
+	for _, entries := range [][]zombieEntry{r.Zombies, r.Suppressed} {
+		for i := range entries {
+			if root, ok := roots[entries[i].UID]; ok {
+				entries[i].Root = &root
+			}
+		}
+	}
+}
+
+// addFailures records the failures that make the scan incomplete.
+func (r *report) addFailures(failures scanFailures) {
+	r.Scan.DiscoveryFailures = append(r.Scan.DiscoveryFailures, failures.discovery...)
+	r.ScanErrors = append(r.ScanErrors, failures.lists...)
+}
+
+// incomplete returns true if some resources could not be discovered or listed.
+func (r *report) incomplete() bool {
+	return len(r.Scan.DiscoveryFailures) > 0 || len(r.ScanErrors) > 0
+}
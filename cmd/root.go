@@ -6,144 +6,349 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/olekukonko/tablewriter"
-	"github.com/olekukonko/tablewriter/renderer"
-	"github.com/olekukonko/tablewriter/tw"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/push"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
+// version is the version of zombie-detector.
+const version = "1.1.4"
+
 var (
 	rootCmd = &cobra.Command{
 		Use:     "zombie-detector",
 		Short:   "zombie-detector detects longly undeleted kubernetes resources",
 		RunE:    rootMain,
-		Version: "1.1.4",
+		Version: version,
 	}
 )
 
 var thresholdFlag time.Duration
 var pushgatewayEndpointFlag string
+var outputFlag string
+var clusterFlag string
+var namespaceFlag string
+var includeNamespacesFlag []string
+var excludeNamespacesFlag []string
+var namespaceSelectorFlag string
+var clusterScopedFlag bool
+var includeResourcesFlag []string
+var excludeResourcesFlag []string
+var configFlag string
+var selectorFlag string
+var annotationSelectorFlag string
+var failOnScanErrorsFlag bool
+var exitCodeFlag bool
+var chunkSizeFlag int64
+var concurrencyFlag int
+var qpsFlag float32
+var burstFlag int
 
 func init() {
-	rootCmd.Flags().DurationVar(&thresholdFlag, "threshold", time.Duration(24*time.Hour), "threshold of detection")
-	rootCmd.MarkFlagRequired("threshold")
-	rootCmd.Flags().StringVar(&pushgatewayEndpointFlag, "pushgateway", "", "URL of Pushgateway's endpoint. If this flag is not given, the result outputs to stdout")
+	rootCmd.PersistentFlags().DurationVar(&thresholdFlag, "threshold", time.Duration(24*time.Hour), "threshold of detection")
+	rootCmd.MarkPersistentFlagRequired("threshold")
+	rootCmd.Flags().StringVar(&pushgatewayEndpointFlag, "pushgateway", "", "URL of Pushgateway's endpoint. If neither this flag nor --textfile is given, the result outputs to stdout")
+	rootCmd.Flags().StringVarP(&outputFlag, "output", "o", outputTable, "output format of the result printed to stdout. One of: table, json, yaml, csv, ndjson, go-template=TEMPLATE, jsonpath=EXPRESSION, custom-columns=SPEC")
+	rootCmd.Flags().BoolVar(&failOnScanErrorsFlag, "fail-on-scan-errors", false, "exit with an error after reporting the result if some resources could not be discovered or listed")
+	rootCmd.Flags().BoolVar(&exitCodeFlag, "exit-code", false, "exit with 1 if zombies are found, 2 if the scan is incomplete, and 3 on fatal errors")
+	rootCmd.PersistentFlags().StringVar(&clusterFlag, "cluster", "", "name of the cluster recorded in the report. Defaults to the URL of the API server")
+	rootCmd.PersistentFlags().StringVarP(&namespaceFlag, "namespace", "n", "", "if given, only namespaced resources in this namespace are scanned")
+	rootCmd.PersistentFlags().StringSliceVar(&includeNamespacesFlag, "include-namespaces", nil, "namespaces to be scanned. Glob patterns and regular expressions enclosed in slashes like /^tenant-/ are accepted")
+	rootCmd.PersistentFlags().StringSliceVar(&excludeNamespacesFlag, "exclude-namespaces", nil, "namespaces not to be scanned. Glob patterns and regular expressions enclosed in slashes like /^kube-/ are accepted")
+	rootCmd.PersistentFlags().StringVar(&namespaceSelectorFlag, "namespace-selector", "", "label selector of namespaces to be scanned")
+	rootCmd.PersistentFlags().BoolVar(&clusterScopedFlag, "cluster-scoped", true, "scan cluster-scoped resources")
+	rootCmd.PersistentFlags().StringSliceVar(&includeResourcesFlag, "include-resources", nil, "resources to be scanned in the form of GROUP, GROUP/RESOURCE or GROUP/VERSION/RESOURCE. Wildcards are accepted and the core group is written as \"core\"")
+	rootCmd.PersistentFlags().StringSliceVar(&excludeResourcesFlag, "exclude-resources", defaultExcludeResources, "resources not to be scanned in the same form as --include-resources")
+	rootCmd.PersistentFlags().StringVarP(&selectorFlag, "selector", "l", "", "label selector of objects to be scanned")
+	rootCmd.PersistentFlags().StringVar(&annotationSelectorFlag, "annotation-selector", "", "annotation selector of objects to be scanned. Supports comma-separated key, !key, key=value and key!=value")
+	rootCmd.PersistentFlags().Int64Var(&chunkSizeFlag, "chunk-size", 500, "maximum number of objects in a response of a list request. 0 disables pagination")
+	rootCmd.PersistentFlags().IntVar(&concurrencyFlag, "concurrency", 1, "number of resources listed in parallel")
+	rootCmd.PersistentFlags().Float32Var(&qpsFlag, "qps", 20, "maximum queries per second to the API server. A negative value disables the rate limit")
+	rootCmd.PersistentFlags().IntVar(&burstFlag, "burst", 30, "maximum burst of queries to the API server")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "path to the configuration file")
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+	err := rootCmd.Execute()
+	if err == nil {
+		return
+	}
+	exitErr := &exitError{}
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	}
+	if exitCodeFlag {
+		log.Print(err)
+		os.Exit(exitFatal)
 	}
+	log.Fatal(err)
 }
 
 type resourceMetadata struct {
-	version           string
-	kind              string
-	name              string
-	namespace         string
+	uid         types.UID
+	version     string
+	kind        string
+	name        string
+	namespace   string
+	labels      map[string]string
+	annotations map[string]string
+	finalizers  []string
+	// finalizerManagers is set only for objects being deleted, so that managedFields of other objects are not retained.
+	finalizerManagers []finalizerManager
+	ownerReferences   []metav1.OwnerReference
 	deletionTimestamp *metav1.Time
+
+	// rule and threshold are the threshold rule applied to a zombie.
+	rule      string
+	threshold time.Duration
+	// suppressedBy is set if a zombie is suppressed by annotations.
+	suppressedBy string
+	// namespaceStatus is set for Namespace zombies.
+	namespaceStatus *namespaceStatus
 }
 
-var IgnoreResources = []schema.GroupVersionResource{
-	{
-		Group:    "metrics.k8s.io",
-		Version:  "v1beta1",
-		Resource: "pods",
-	},
-	{
-		Group:    "metrics.k8s.io",
-		Version:  "v1beta1",
-		Resource: "nodes",
-	},
+// scanOptions controls which objects are scanned.
+// The zero value scans all objects in the cluster.
+type scanOptions struct {
+	namespaces namespaceFilter
+	resources  resourceFilter
+	// labelSelector is passed to the API server, while annotations are matched on the client side.
+	labelSelector string
+	annotations   annotationSelector
+	// concurrency is the number of resources listed in parallel. 0 is the same as 1.
+	concurrency int
+	// chunkSize is the maximum number of objects in a response of a list request. 0 disables pagination.
+	chunkSize int64
 }
 
-func getAllResources(ctx context.Context, config *rest.Config) ([]resourceMetadata, error) {
-	o, err := discovery.NewDiscoveryClientForConfig(config)
+func newScanOptions(cmd *cobra.Command, fileCfg *fileConfig) (scanOptions, error) {
+	if chunkSizeFlag < 0 {
+		return scanOptions{}, errors.New("--chunk-size must not be negative")
+	}
+	if concurrencyFlag < 1 {
+		return scanOptions{}, errors.New("--concurrency must be positive")
+	}
+	opts := scanOptions{chunkSize: chunkSizeFlag, concurrency: concurrencyFlag}
+	include, err := parseNamePatterns(includeNamespacesFlag)
+	if err != nil {
+		return opts, err
+	}
+	exclude, err := parseNamePatterns(excludeNamespacesFlag)
+	if err != nil {
+		return opts, err
+	}
+	opts.namespaces = namespaceFilter{
+		namespace:         namespaceFlag,
+		include:           include,
+		exclude:           exclude,
+		skipClusterScoped: !clusterScopedFlag,
+	}
+	if namespaceSelectorFlag != "" {
+		selector, err := labels.Parse(namespaceSelectorFlag)
+		if err != nil {
+			return opts, fmt.Errorf("invalid namespace selector: %w", err)
+		}
+		opts.namespaces.selector = selector
+	}
+
+	includeResources := includeResourcesFlag
+	if !cmd.Flags().Changed("include-resources") && len(fileCfg.Resources.Include) > 0 {
+		includeResources = fileCfg.Resources.Include
+	}
+	excludeResources := excludeResourcesFlag
+	if !cmd.Flags().Changed("exclude-resources") && fileCfg.Resources.Exclude != nil {
+		excludeResources = fileCfg.Resources.Exclude
+	}
+	opts.resources.include, err = parseResourcePatterns(includeResources)
+	if err != nil {
+		return opts, err
+	}
+	opts.resources.exclude, err = parseResourcePatterns(excludeResources)
+	if err != nil {
+		return opts, err
+	}
+
+	if selectorFlag != "" {
+		selector, err := labels.Parse(selectorFlag)
+		if err != nil {
+			return opts, fmt.Errorf("invalid selector: %w", err)
+		}
+		opts.labelSelector = selector.String()
+	}
+	opts.annotations, err = parseAnnotationSelector(annotationSelectorFlag)
+	if err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// defaultExcludeResources are resources that are not scanned by default.
+// PodMetrics and NodeMetrics are not persisted objects and are generated on every request.
+// The whole group version is excluded, so that a failure of the metrics adapter is ignored too.
+var defaultExcludeResources = []string{
+	"metrics.k8s.io/v1beta1/*",
+}
+
+func selectNamespaces(ctx context.Context, dynamicClient dynamic.Interface, selector labels.Selector) (map[string]bool, error) {
+	gvr := corev1.SchemeGroupVersion.WithResource("namespaces")
+	namespaces, err := dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
 	if err != nil {
 		return nil, err
 	}
+	selected := make(map[string]bool, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		selected[ns.GetName()] = true
+	}
+	return selected, nil
+}
+
+// scanResult is the outcome of listing resources other than the objects themselves.
+type scanResult struct {
+	failures scanFailures
+	// objects is the number of objects listed for each resource, including those filtered out.
+	objects map[schema.GroupResource]int
+}
+
+// listResources lists objects to be scanned, and calls visit for each object.
+// Objects are streamed to visit, so that the caller can keep only the objects it needs.
+// Failures that do not abort the scan are returned in scanResult.
+func listResources(ctx context.Context, config *rest.Config, opts scanOptions, visit func(resourceMetadata)) (scanResult, error) {
+	result := scanResult{objects: make(map[schema.GroupResource]int)}
+	failures := &result.failures
+	o, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return result, err
+	}
 	dynamicClient, err := dynamic.NewForConfig(config)
 	if err != nil {
-		return nil, err
+		return result, err
 	}
+	// Only metadata of objects is needed to detect zombies, so objects are listed as PartialObjectMetadata to reduce the load.
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return result, err
+	}
+	// An unavailable aggregated API fails the discovery of its group, but resources in other groups are still returned and scanned.
 	serverResources, err := o.ServerPreferredResources()
 	if err != nil {
-		return nil, err
+		var ok bool
+		failures.discovery, ok = newDiscoveryFailures(err)
+		if !ok {
+			return result, err
+		}
+		// Group versions excluded by the resource filter would not be scanned, so their failures do not make the scan incomplete.
+		failures.discovery = slices.DeleteFunc(failures.discovery, func(f discoveryFailure) bool {
+			gv, err := schema.ParseGroupVersion(f.GroupVersion)
+			return err == nil && opts.resources.skipsGroupVersion(gv)
+		})
+		for _, f := range failures.discovery {
+			fmt.Fprintf(os.Stderr, "failed to discover %s: %s\n", f.GroupVersion, f.Error)
+		}
 	}
-	resources := make([]resourceMetadata, 0)
+	// Create-only resources such as tokenreviews cannot hold objects, and listing them is forbidden by narrowly scoped RBAC roles before it is rejected as not supported.
+	serverResources = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list"}}, serverResources)
+	nsFilter := opts.namespaces
+	if nsFilter.selector != nil {
+		nsFilter.selected, err = selectNamespaces(ctx, dynamicClient, nsFilter.selector)
+		if err != nil {
+			return result, err
+		}
+	}
+	// visit and result are shared by multiple workers.
+	var mu sync.Mutex
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(opts.concurrency, 1))
 	for _, resList := range serverResources {
 		gv, err := schema.ParseGroupVersion(resList.GroupVersion)
 		if err != nil {
 			gv = schema.GroupVersion{}
 		}
-	L:
 		for _, resource := range resList.APIResources {
 			groupResourceDef := schema.GroupVersionResource{Group: gv.Group, Version: gv.Version, Resource: resource.Name}
-			for _, ir := range IgnoreResources {
-				if ir == groupResourceDef {
-					fmt.Printf("ignoring %s %s %s\n", groupResourceDef.Group, groupResourceDef.Version, groupResourceDef.Resource)
-					continue L
-				}
+			if opts.resources.excluded(groupResourceDef) {
+				fmt.Fprintf(os.Stderr, "ignoring %s %s %s\n", groupResourceDef.Group, groupResourceDef.Version, groupResourceDef.Resource)
+				continue
 			}
-			listResponse, err := dynamicClient.Resource(groupResourceDef).Namespace(corev1.NamespaceAll).List(ctx, metav1.ListOptions{})
-			statusErr := &apierrors.StatusError{}
-			if err != nil && !errors.As(err, &statusErr) {
-				return nil, err
+			if !opts.resources.included(groupResourceDef) {
+				continue
 			}
-			if statusErr.ErrStatus.Reason == metav1.StatusReasonNotFound || statusErr.ErrStatus.Reason == metav1.StatusReasonMethodNotAllowed {
+			namespace := corev1.NamespaceAll
+			if resource.Namespaced {
+				namespace = nsFilter.namespace
+			} else if nsFilter.skipClusterScoped {
 				continue
 			}
-			for _, item := range listResponse.Items {
-				resources = append(resources, resourceMetadata{
-					version:           item.GetAPIVersion(),
-					kind:              item.GetKind(),
-					name:              item.GetName(),
-					namespace:         item.GetNamespace(),
-					deletionTimestamp: item.GetDeletionTimestamp(),
+			g.Go(func() error {
+				ri := metadataClient.Resource(groupResourceDef).Namespace(namespace)
+				listed := 0
+				err := listChunked(ctx, ri, metav1.ListOptions{LabelSelector: opts.labelSelector}, opts.chunkSize, func(item *metav1.PartialObjectMetadata) {
+					listed++
+					if resource.Namespaced && !nsFilter.match(item.GetNamespace()) {
+						return
+					}
+					if !opts.annotations.match(item.GetAnnotations()) {
+						return
+					}
+					var managers []finalizerManager
+					if item.GetDeletionTimestamp() != nil {
+						managers = finalizerManagers(item.GetFinalizers(), item.GetManagedFields())
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					visit(resourceMetadata{
+						uid:               item.GetUID(),
+						version:           gv.String(),
+						kind:              resource.Kind,
+						name:              item.GetName(),
+						namespace:         item.GetNamespace(),
+						labels:            item.GetLabels(),
+						annotations:       item.GetAnnotations(),
+						finalizers:        item.GetFinalizers(),
+						finalizerManagers: managers,
+						ownerReferences:   item.GetOwnerReferences(),
+						deletionTimestamp: item.GetDeletionTimestamp(),
+					})
 				})
-			}
+				mu.Lock()
+				result.objects[groupResourceDef.GroupResource()] += listed
+				mu.Unlock()
+				if err == nil || apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+					return nil
+				}
+				// An error of a resource does not abort the scan unless the scan itself is canceled.
+				if ctx.Err() != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "failed to list %s: %v\n", groupResourceDef, err)
+				mu.Lock()
+				defer mu.Unlock()
+				failures.lists = append(failures.lists, newListFailure(groupResourceDef, err))
+				return nil
+			})
 		}
 	}
-	return resources, nil
-}
-
-func printAllResources(resources []resourceMetadata) {
-	data := make([][]string, 0, len(resources))
-	for _, res := range resources {
-		data = append(data, []string{res.version, res.kind, res.name, res.namespace, res.deletionTimestamp.String()})
-	}
-	table := tablewriter.NewTable(os.Stdout,
-		tablewriter.WithRenderer(renderer.NewBlueprint(tw.Rendition{
-			Borders: tw.BorderNone,
-			Settings: tw.Settings{
-				Separators: tw.SeparatorsNone,
-				Lines:      tw.LinesNone,
-			},
-		})),
-		tablewriter.WithConfig(tablewriter.Config{
-			Header: tw.CellConfig{
-				Formatting: tw.CellFormatting{Alignment: tw.AlignLeft},
-			},
-			Row: tw.CellConfig{
-				Formatting: tw.CellFormatting{Alignment: tw.AlignLeft},
-			},
-		}),
-	)
-	table.Header("Version", "Kind", "Name", "Namespace", "Timestamp")
-	table.Bulk(data)
-	table.Render()
+	if err := g.Wait(); err != nil {
+		return result, err
+	}
+	sortListFailures(failures.lists)
+	return result, nil
 }
 
 func detectZombieResource(resource resourceMetadata, threshold time.Duration) bool {
@@ -156,72 +361,344 @@ func detectZombieResource(resource resourceMetadata, threshold time.Duration) bo
 	return false
 }
 
-func detectZombieResources(resources []resourceMetadata, threshold time.Duration) []resourceMetadata {
+func detectZombieResources(resources []resourceMetadata, rules thresholdRules) []resourceMetadata {
 	zombieResources := make([]resourceMetadata, 0)
 	for _, res := range resources {
+		if res.deletionTimestamp == nil {
+			continue
+		}
+		rule, threshold := rules.lookup(res)
 		isZombie := detectZombieResource(res, threshold)
 		if isZombie {
+			res.rule = rule
+			res.threshold = threshold
 			zombieResources = append(zombieResources, res)
 		}
 	}
 	return zombieResources
 }
 
-func postZombieResourcesMetrics(zombieResources []resourceMetadata, endpoint string) error {
-	err := push.New(endpoint, "zombie-detector").Delete()
-	if err != nil {
-		return err
+func newZombieGauges(entries []zombieEntry, name, help string) []prometheus.Gauge {
+	gauges := make([]prometheus.Gauge, 0, len(entries))
+	for _, z := range entries {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: name,
+			Help: help,
+			ConstLabels: map[string]string{
+				"apiVersion": z.APIVersion,
+				"kind":       z.Kind,
+				"name":       z.Name,
+				"namespace":  z.Namespace,
+				"rule":       z.Rule,
+				"reason":     z.reason(),
+			},
+		})
+		gauge.Set(z.AgeSeconds)
+		gauges = append(gauges, gauge)
 	}
-	if len(zombieResources) == 0 {
-		return nil
+	return gauges
+}
+
+// newZombieSummaryGauges returns the number of zombies for each group, kind and namespace,
+// and the duration of the oldest zombie for each kind.
+// They are series of low cardinality that dashboards and alerts can use without aggregating the series of each zombie.
+func newZombieSummaryGauges(entries []zombieEntry) []prometheus.Gauge {
+	type resourceKey struct {
+		group     string
+		kind      string
+		namespace string
 	}
-	gauges := make([]prometheus.Gauge, 0)
-	for _, res := range zombieResources {
+	counts := make(map[resourceKey]int)
+	oldest := make(map[string]float64)
+	for _, z := range entries {
+		gv, err := schema.ParseGroupVersion(z.APIVersion)
+		if err != nil {
+			continue
+		}
+		counts[resourceKey{group: gv.Group, kind: z.Kind, namespace: z.Namespace}]++
+		if age, ok := oldest[z.Kind]; !ok || z.AgeSeconds > age {
+			oldest[z.Kind] = z.AgeSeconds
+		}
+	}
+
+	gauges := make([]prometheus.Gauge, 0, len(counts)+len(oldest))
+	for k, count := range counts {
 		gauge := prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: "zombie_duration_seconds",
-			Help: "zombie detector zombie duration",
+			Name: "zombie_resources_total",
+			Help: "zombie detector number of zombies",
 			ConstLabels: map[string]string{
-				"apiVersion": res.version,
-				"kind":       res.kind,
-				"name":       res.name,
-				"namespace":  res.namespace,
-				"updated_at": time.Now().Format(time.RFC3339),
+				"group":     k.group,
+				"kind":      k.kind,
+				"namespace": k.namespace,
 			},
 		})
-		gauge.Set(time.Since(res.deletionTimestamp.Time).Seconds())
+		gauge.Set(float64(count))
 		gauges = append(gauges, gauge)
 	}
+	for kind, age := range oldest {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "zombie_oldest_duration_seconds",
+			Help: "zombie detector duration of the oldest zombie",
+			ConstLabels: map[string]string{
+				"kind": kind,
+			},
+		})
+		gauge.Set(age)
+		gauges = append(gauges, gauge)
+	}
+	return gauges
+}
+
+// newFinalizerGauges returns a series for each finalizer blocking the deletion of zombies.
+func newFinalizerGauges(entries []zombieEntry) []prometheus.Gauge {
+	gauges := make([]prometheus.Gauge, 0)
+	for _, z := range entries {
+		for _, f := range z.FinalizerManagers {
+			gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+				Name: "zombie_finalizer_info",
+				Help: "zombie detector finalizers of zombies",
+				ConstLabels: map[string]string{
+					"apiVersion": z.APIVersion,
+					"kind":       z.Kind,
+					"name":       z.Name,
+					"namespace":  z.Namespace,
+					"rule":       z.Rule,
+					"finalizer":  f.Finalizer,
+					"manager":    strings.Join(f.Managers, ","),
+				},
+			})
+			gauge.Set(1)
+			gauges = append(gauges, gauge)
+		}
+	}
+	return gauges
+}
+
+// newReportGauges returns the metrics of a report, which are pushed to Pushgateway or exposed by the serve subcommand.
+func newReportGauges(r *report) []prometheus.Gauge {
+	gauges := newZombieGauges(r.Zombies, "zombie_duration_seconds", "zombie detector zombie duration")
+	gauges = append(gauges, newZombieGauges(r.Suppressed, "zombie_suppressed_duration_seconds", "zombie detector duration of zombies suppressed by annotations")...)
+	gauges = append(gauges, newZombieSummaryGauges(r.Zombies)...)
+	gauges = append(gauges, newFinalizerGauges(r.Zombies)...)
+	discoveryFailures := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "zombie_detector_discovery_failures",
+		Help: "zombie detector number of API group versions that failed to be discovered and were not scanned",
+	})
+	discoveryFailures.Set(float64(len(r.Scan.DiscoveryFailures)))
+	gauges = append(gauges, discoveryFailures)
+	scanDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "zombie_detector_scan_duration_seconds",
+		Help: "zombie detector duration of the last completed scan",
+	})
+	scanDuration.Set(r.Scan.EndTime.Sub(r.Scan.StartTime).Seconds())
+	gauges = append(gauges, scanDuration)
+	return gauges
+}
+
+// newListErrorsCounter returns a counter of resources that could not be listed.
+func newListErrorsCounter() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zombie_detector_list_errors_total",
+		Help: "zombie detector number of errors on listing resources",
+	}, []string{"group", "resource", "reason"})
+}
+
+func addListErrors(counter *prometheus.CounterVec, failures []listFailure) {
+	for _, f := range failures {
+		counter.WithLabelValues(f.Group, f.Resource, f.Reason).Inc()
+	}
+}
+
+// newObjectsScannedCounter returns a counter of objects listed from the API server.
+func newObjectsScannedCounter() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zombie_detector_objects_scanned_total",
+		Help: "zombie detector number of objects scanned",
+	}, []string{"group", "resource"})
+}
+
+func addObjectsScanned(counter *prometheus.CounterVec, objects map[schema.GroupResource]int) {
+	for gr, count := range objects {
+		counter.WithLabelValues(gr.Group, gr.Resource).Add(float64(count))
+	}
+}
+
+// newBuildInfoGauge returns a series that is always 1 with the version of zombie-detector.
+func newBuildInfoGauge() prometheus.Gauge {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "zombie_detector_build_info",
+		Help: "zombie detector build information",
+		ConstLabels: map[string]string{
+			"version":   version,
+			"goversion": runtime.Version(),
+		},
+	})
+	gauge.Set(1)
+	return gauge
+}
+
+// newLastSuccessGauge returns the time when the last complete scan finished.
+func newLastSuccessGauge(t time.Time) prometheus.Gauge {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "zombie_detector_last_success_timestamp_seconds",
+		Help: "zombie detector time when the last successful scan completed, in seconds since the epoch",
+	})
+	gauge.Set(float64(t.UnixNano()) / 1e9)
+	return gauge
+}
+
+// newReportRegistry returns a registry of the metrics of a single scan.
+// zombie_detector_last_success_timestamp_seconds is included only if the scan is complete.
+func newReportRegistry(r *report) *prometheus.Registry {
 	registry := prometheus.NewRegistry()
-	for _, g := range gauges {
+	for _, g := range newReportGauges(r) {
 		registry.MustRegister(g)
 	}
-	err = push.New(endpoint, "zombie-detector").Gatherer(registry).Add()
+	listErrors := newListErrorsCounter()
+	addListErrors(listErrors, r.ScanErrors)
+	objectsScanned := newObjectsScannedCounter()
+	addObjectsScanned(objectsScanned, r.objectsScanned)
+	registry.MustRegister(listErrors, objectsScanned, newBuildInfoGauge())
+	if !r.incomplete() {
+		registry.MustRegister(newLastSuccessGauge(r.Scan.EndTime))
+	}
+	return registry
+}
+
+// postZombieResourcesMetrics replaces the metrics in the group of the client with those of the report at once.
+func postZombieResourcesMetrics(r *report, client *pushgatewayClient) error {
+	return client.pusher().Gatherer(newReportRegistry(r)).Push()
+}
+
+// scanner scans a cluster with the settings given by flags.
+type scanner struct {
+	config  *rest.Config
+	opts    scanOptions
+	rules   thresholdRules
+	cluster string
+}
+
+func newScanner(cmd *cobra.Command) (*scanner, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.QPS = qpsFlag
+	cfg.Burst = burstFlag
+	fileCfg, err := loadConfig(configFlag)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	opts, err := newScanOptions(cmd, fileCfg)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := newThresholdRules(thresholdFlag, fileCfg.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	cluster := clusterFlag
+	if cluster == "" {
+		cluster = cfg.Host
+	}
+	return &scanner{config: cfg, opts: opts, rules: rules, cluster: cluster}, nil
+}
+
+func (s *scanner) scan(ctx context.Context) (*report, error) {
+	scanStart := time.Now()
+	// Only objects being deleted are kept, because other objects are neither zombies nor in zombie trees.
+	// A list restarted by an expired continue token visits objects again, so they are deduplicated by UID.
+	deletingResources := make([]resourceMetadata, 0)
+	deletingUIDs := make(map[types.UID]bool)
+	result, err := listResources(ctx, s.config, s.opts, func(res resourceMetadata) {
+		if res.deletionTimestamp == nil || deletingUIDs[res.uid] {
+			return
+		}
+		deletingUIDs[res.uid] = true
+		deletingResources = append(deletingResources, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	zombieResources := detectZombieResources(deletingResources, s.rules)
+	dynamicClient, err := dynamic.NewForConfig(s.config)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(s.config)
+	if err != nil {
+		return nil, err
+	}
+	metadataClient, err := metadata.NewForConfig(s.config)
+	if err != nil {
+		return nil, err
+	}
+	diagnoseNamespaces(ctx, discoveryClient, dynamicClient, metadataClient, s.opts.chunkSize, zombieResources)
+	nsAnnotations := getNamespaceAnnotations(ctx, dynamicClient, zombieResources)
+	zombieResources, suppressedResources := splitSuppressedResources(zombieResources, nsAnnotations, time.Now())
+	zombieUIDs := make(map[types.UID]bool, len(zombieResources))
+	for _, res := range zombieResources {
+		zombieUIDs[res.uid] = true
+	}
+	trees := buildZombieTrees(deletingResources, zombieUIDs)
+	scanEnd := time.Now()
+
+	r := newReport(zombieResources, suppressedResources, s.cluster, s.rules.fallback, scanStart, scanEnd)
+	r.addTrees(trees)
+	r.addFailures(result.failures)
+	r.objectsScanned = result.objects
+	return r, nil
 }
 
 func rootMain(cmd *cobra.Command, args []string) error {
-	config, err := config.GetConfig()
+	printer, err := newReportPrinter(outputFlag)
 	if err != nil {
 		return err
 	}
-	ctx := context.Background()
-	allResources, err := getAllResources(ctx, config)
+	var pushgateway *pushgatewayClient
+	if pushgatewayEndpointFlag != "" {
+		pushgateway, err = newPushgatewayClient(pushgatewayEndpointFlag)
+		if err != nil {
+			return err
+		}
+	}
+	if textfileFlag != "" {
+		if err := checkTextfileDir(textfileFlag); err != nil {
+			return err
+		}
+	}
+	s, err := newScanner(cmd)
 	if err != nil {
 		return err
 	}
-	zombieResources := detectZombieResources(allResources, thresholdFlag)
-
-	if pushgatewayEndpointFlag == "" {
-		printAllResources(zombieResources)
-		return nil
+	r, err := s.scan(context.Background())
+	if err != nil {
+		return err
+	}
+	if textfileFlag != "" {
+		if err := writeZombieResourcesMetrics(r, textfileFlag); err != nil {
+			return err
+		}
+	}
+	// With --textfile, the result is printed only if the output format is given explicitly.
+	if pushgateway != nil {
+		err = postZombieResourcesMetrics(r, pushgateway)
+	} else if textfileFlag == "" || cmd.Flags().Changed("output") {
+		err = printer(os.Stdout, r)
 	}
-	err = postZombieResourcesMetrics(zombieResources, pushgatewayEndpointFlag)
 	if err != nil {
 		return err
 	}
-
+	if exitCodeFlag {
+		if code := reportExitCode(r); code != exitClean {
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+			return &exitError{code: code}
+		}
+		return nil
+	}
+	if failOnScanErrorsFlag && r.incomplete() {
+		return fmt.Errorf("scan is incomplete: %d group versions failed to be discovered and %d resources failed to be listed", len(r.Scan.DiscoveryFailures), len(r.ScanErrors))
+	}
 	return nil
 }
@@ -15,8 +15,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -259,9 +262,9 @@ func TestDetectZombieResource(t *testing.T) {
 var _ = Describe("Test zombie-detector", func() {
 	ctx := context.Background()
 	It("should not detect anything", func() {
-		allResources, err := getAllResources(ctx, cfg)
+		allResources, err := getAllResources(ctx, cfg, scanOptions{})
 		Expect(err).NotTo(HaveOccurred())
-		zombieResources := detectZombieResources(allResources, testThreshold)
+		zombieResources := detectZombieResources(allResources, testRules)
 		Expect(zombieResources).To(BeEmpty())
 	})
 
@@ -320,9 +323,9 @@ var _ = Describe("Test zombie-detector", func() {
 		}).Should(Succeed())
 
 		By("detecting zombie pod")
-		allResources, err := getAllResources(ctx, cfg)
+		allResources, err := getAllResources(ctx, cfg, scanOptions{})
 		Expect(err).NotTo(HaveOccurred())
-		zombieResources := detectZombieResources(allResources, testThreshold)
+		zombieResources := detectZombieResources(allResources, testRules)
 		Expect(len(zombieResources)).To(Equal(2))
 
 		By("checking finalizers and deletionTimestamp exist")
@@ -342,9 +345,119 @@ var _ = Describe("Test zombie-detector", func() {
 			Expect(ok).To(BeTrue())
 			finalizers := obj.GetFinalizers()
 			Expect(finalizers).NotTo(BeEmpty())
+			Expect(zombieResources[i].finalizers).To(Equal(finalizers))
 
 			Expect(obj.GetDeletionTimestamp()).NotTo(BeNil())
 			Expect(zombieResources[i].deletionTimestamp).NotTo(BeNil())
 		}
 	})
+
+	It("should filter zombie resources by namespace", func() {
+		By("excluding the test namespace")
+		exclude, err := parseNamePatterns([]string{"te*"})
+		Expect(err).NotTo(HaveOccurred())
+		allResources, err := getAllResources(ctx, cfg, scanOptions{namespaces: namespaceFilter{exclude: exclude}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(detectZombieResources(allResources, testRules)).To(BeEmpty())
+
+		By("scanning only the test namespace")
+		allResources, err = getAllResources(ctx, cfg, scanOptions{namespaces: namespaceFilter{namespace: "test", skipClusterScoped: true}})
+		Expect(err).NotTo(HaveOccurred())
+		for _, res := range allResources {
+			Expect(res.namespace).To(Equal("test"))
+		}
+		Expect(detectZombieResources(allResources, testRules)).To(HaveLen(2))
+
+		By("selecting namespaces by labels")
+		selector, err := labels.Parse("kubernetes.io/metadata.name=test")
+		Expect(err).NotTo(HaveOccurred())
+		allResources, err = getAllResources(ctx, cfg, scanOptions{namespaces: namespaceFilter{selector: selector}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(detectZombieResources(allResources, testRules)).To(HaveLen(2))
+	})
+
+	It("should filter zombie resources by resource", func() {
+		By("excluding pods")
+		exclude, err := parseResourcePatterns([]string{"core/pods"})
+		Expect(err).NotTo(HaveOccurred())
+		allResources, err := getAllResources(ctx, cfg, scanOptions{resources: resourceFilter{exclude: exclude}})
+		Expect(err).NotTo(HaveOccurred())
+		zombieResources := detectZombieResources(allResources, testRules)
+		Expect(zombieResources).To(HaveLen(1))
+		Expect(zombieResources[0].kind).To(Equal("ConfigMap"))
+
+		By("including only the apps group")
+		include, err := parseResourcePatterns([]string{"apps"})
+		Expect(err).NotTo(HaveOccurred())
+		allResources, err = getAllResources(ctx, cfg, scanOptions{resources: resourceFilter{include: include}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(detectZombieResources(allResources, testRules)).To(BeEmpty())
+	})
+
+	It("should filter zombie resources by labels and annotations", func() {
+		By("selecting by labels")
+		allResources, err := getAllResources(ctx, cfg, scanOptions{labelSelector: "app=test"})
+		Expect(err).NotTo(HaveOccurred())
+		zombieResources := detectZombieResources(allResources, testRules)
+		Expect(zombieResources).To(HaveLen(1))
+		Expect(zombieResources[0].name).To(Equal("test-pod"))
+
+		By("selecting by annotations")
+		annotations, err := parseAnnotationSelector("example.com/not-exist")
+		Expect(err).NotTo(HaveOccurred())
+		allResources, err = getAllResources(ctx, cfg, scanOptions{annotations: annotations})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allResources).To(BeEmpty())
+	})
+
+	It("should list resources in chunks and in parallel", func() {
+		sequential, err := getAllResources(ctx, cfg, scanOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		parallel, err := getAllResources(ctx, cfg, scanOptions{chunkSize: 1, concurrency: 4})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parallel).To(ConsistOf(sequential))
+	})
+
+	It("should count scanned objects", func() {
+		var visited int
+		result, err := listResources(ctx, cfg, scanOptions{}, func(resourceMetadata) {
+			visited++
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.objects).To(HaveKeyWithValue(schema.GroupResource{Resource: "namespaces"}, BeNumerically(">", 0)))
+		Expect(result.objects).NotTo(HaveKey(schema.GroupResource{Group: "authentication.k8s.io", Resource: "tokenreviews"}))
+		var listed int
+		for _, count := range result.objects {
+			listed += count
+		}
+		Expect(listed).To(Equal(visited))
+	})
+
+	It("should suppress zombie resources by annotations", func() {
+		By("annotating the test namespace")
+		testNamespace := corev1.Namespace{}
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: "test"}, &testNamespace)
+		Expect(err).NotTo(HaveOccurred())
+		testNamespace.Annotations = map[string]string{ignoreAnnotation: "true"}
+		err = k8sClient.Update(ctx, &testNamespace)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("detecting suppressed zombie resources")
+		allResources, err := getAllResources(ctx, cfg, scanOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		zombieResources := detectZombieResources(allResources, testRules)
+		dynamicClient, err := dynamic.NewForConfig(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		nsAnnotations := getNamespaceAnnotations(ctx, dynamicClient, zombieResources)
+		active, suppressed := splitSuppressedResources(zombieResources, nsAnnotations, time.Now())
+		Expect(active).To(BeEmpty())
+		Expect(suppressed).To(HaveLen(2))
+
+		By("removing the annotation")
+		err = k8sClient.Get(ctx, types.NamespacedName{Name: "test"}, &testNamespace)
+		Expect(err).NotTo(HaveOccurred())
+		testNamespace.Annotations = nil
+		err = k8sClient.Update(ctx, &testNamespace)
+		Expect(err).NotTo(HaveOccurred())
+	})
 })
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// discoveryFailure is an API group version whose resources could not be discovered.
+// Resources in the group version are not scanned.
+type discoveryFailure struct {
+	GroupVersion string `json:"groupVersion"`
+	Error        string `json:"error"`
+}
+
+// listFailure is a resource that could not be listed, such as one forbidden by RBAC.
+// Objects of the resource may be partially scanned.
+type listFailure struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	// Reason is the reason of the error, such as Forbidden and Timeout.
+	Reason string `json:"reason"`
+	Error  string `json:"error"`
+}
+
+// listFailureUnknown is the reason of an error without a known reason.
+const listFailureUnknown = "Unknown"
+
+func newListFailure(gvr schema.GroupVersionResource, err error) listFailure {
+	return listFailure{
+		Group:    gvr.Group,
+		Version:  gvr.Version,
+		Resource: gvr.Resource,
+		Reason:   listFailureReason(err),
+		Error:    err.Error(),
+	}
+}
+
+// listFailureReason returns the reason of an error returned by the API server, or Timeout for timeouts on the client side.
+func listFailureReason(err error) string {
+	if reason := apierrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return string(reason)
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return string(metav1.StatusReasonTimeout)
+	}
+	return listFailureUnknown
+}
+
+func sortListFailures(failures []listFailure) {
+	slices.SortFunc(failures, func(a, b listFailure) int {
+		return cmp.Or(
+			strings.Compare(a.Group, b.Group),
+			strings.Compare(a.Version, b.Version),
+			strings.Compare(a.Resource, b.Resource),
+		)
+	})
+}
+
+// scanFailures are problems that make a scan incomplete without aborting it.
+type scanFailures struct {
+	discovery []discoveryFailure
+	lists     []listFailure
+}
+
+// newDiscoveryFailures returns the group versions that failed in err.
+// It returns false if err is not a partial failure of discovery.
+func newDiscoveryFailures(err error) ([]discoveryFailure, bool) {
+	groupErr := &discovery.ErrGroupDiscoveryFailed{}
+	if !errors.As(err, &groupErr) {
+		return nil, false
+	}
+	failures := make([]discoveryFailure, 0, len(groupErr.Groups))
+	for gv, err := range groupErr.Groups {
+		failures = append(failures, discoveryFailure{GroupVersion: gv.String(), Error: err.Error()})
+	}
+	slices.SortFunc(failures, func(a, b discoveryFailure) int {
+		return strings.Compare(a.GroupVersion, b.GroupVersion)
+	})
+	return failures, true
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+func TestNewDiscoveryFailures(t *testing.T) {
+	t.Parallel()
+	err := &discovery.ErrGroupDiscoveryFailed{Groups: map[schema.GroupVersion]error{
+		{Group: "metrics.k8s.io", Version: "v1beta1"}:        errors.New("the server is currently unable to handle the request"),
+		{Group: "custom.metrics.k8s.io", Version: "v1beta2"}: errors.New("connection refused"),
+	}}
+	failures, ok := newDiscoveryFailures(fmt.Errorf("failed to discover: %w", err))
+	require.True(t, ok)
+	assert.Equal(t, []discoveryFailure{
+		{GroupVersion: "custom.metrics.k8s.io/v1beta2", Error: "connection refused"},
+		{GroupVersion: "metrics.k8s.io/v1beta1", Error: "the server is currently unable to handle the request"},
+	}, failures)
+
+	_, ok = newDiscoveryFailures(errors.New("connection refused"))
+	assert.False(t, ok)
+}
+
+func TestPrintDiscoveryFailureTable(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := newReport(nil, nil, "", time.Hour, now, now)
+	r.addFailures(scanFailures{discovery: []discoveryFailure{
+		{GroupVersion: "metrics.k8s.io/v1beta1", Error: "the server is currently unable to handle the request"},
+	}})
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, printReportTable(buf, r))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "Discovery failures:", lines[2])
+	assert.Equal(t, []string{"GROUP", "VERSION", "ERROR"}, strings.Fields(lines[3]))
+	assert.Regexp(t, `^\s*metrics.k8s.io/v1beta1\s+the server is currently unable to handle the request\s*$`, lines[4])
+}
+
+func TestListFailureReason(t *testing.T) {
+	t.Parallel()
+	pods := schema.GroupResource{Resource: "pods"}
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{err: apierrors.NewForbidden(pods, "", errors.New("RBAC")), want: "Forbidden"},
+		{err: apierrors.NewUnauthorized("expired token"), want: "Unauthorized"},
+		{err: apierrors.NewTimeoutError("timeout", 1), want: "Timeout"},
+		{err: apierrors.NewInternalError(errors.New("etcd")), want: "InternalError"},
+		{err: apierrors.NewServiceUnavailable("unavailable"), want: "ServiceUnavailable"},
+		{err: fmt.Errorf("list: %w", context.DeadlineExceeded), want: "Timeout"},
+		{err: &net.OpError{Op: "dial", Err: timeoutError{}}, want: "Timeout"},
+		{err: errors.New("connection refused"), want: listFailureUnknown},
+	} {
+		assert.Equal(t, tt.want, listFailureReason(tt.err), tt.err.Error())
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestPrintScanErrorTable(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	failures := []listFailure{
+		newListFailure(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}, apierrors.NewForbidden(schema.GroupResource{Group: "example.com", Resource: "widgets"}, "", errors.New("RBAC"))),
+		newListFailure(schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", errors.New("RBAC"))),
+	}
+	sortListFailures(failures)
+	r := newReport(nil, nil, "", time.Hour, now, now)
+	r.addFailures(scanFailures{lists: failures})
+	assert.True(t, r.incomplete())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, printReportTable(buf, r))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, "Scan errors:", lines[2])
+	assert.Equal(t, []string{"RESOURCE", "VERSION", "REASON", "ERROR"}, strings.Fields(lines[3]))
+	assert.Regexp(t, `^\s*secrets\s+v1\s+Forbidden\s+secrets is forbidden: RBAC\s*$`, lines[4])
+	assert.Regexp(t, `^\s*widgets.example.com\s+v1\s+Forbidden\s+`, lines[5])
+}
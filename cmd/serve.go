@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "rescan the cluster periodically and expose the result on an HTTP endpoint",
+		Long: `serve rescans the cluster periodically and exposes the metrics of zombies on /metrics for Prometheus to scrape.
+/healthz reports that the server is running, and /readyz reports that the first scan has completed.`,
+		Args: cobra.NoArgs,
+		RunE: serveMain,
+	}
+)
+
+var listenAddressFlag string
+var intervalFlag time.Duration
+
+func init() {
+	serveCmd.Flags().StringVar(&listenAddressFlag, "listen-address", ":8080", "address to listen on for /metrics, /healthz and /readyz")
+	serveCmd.Flags().DurationVar(&intervalFlag, "interval", 5*time.Minute, "interval of scans")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// serveState holds the result of the latest successful scan.
+// It is a prometheus.Collector that exposes the metrics of the report.
+type serveState struct {
+	mu     sync.RWMutex
+	report *report
+	// lastSuccess is the end time of the latest complete scan, which is kept over incomplete scans.
+	lastSuccess time.Time
+	// listErrors and objectsScanned accumulate the numbers of all scans.
+	listErrors     *prometheus.CounterVec
+	objectsScanned *prometheus.CounterVec
+}
+
+func newServeState() *serveState {
+	return &serveState{
+		listErrors:     newListErrorsCounter(),
+		objectsScanned: newObjectsScannedCounter(),
+	}
+}
+
+func (s *serveState) update(r *report) {
+	addListErrors(s.listErrors, r.ScanErrors)
+	addObjectsScanned(s.objectsScanned, r.objectsScanned)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report = r
+	if !r.incomplete() {
+		s.lastSuccess = r.Scan.EndTime
+	}
+}
+
+func (s *serveState) ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.report != nil
+}
+
+// Describe sends no descriptors, because the series change with every scan.
+func (s *serveState) Describe(chan<- *prometheus.Desc) {}
+
+func (s *serveState) Collect(ch chan<- prometheus.Metric) {
+	s.mu.RLock()
+	r := s.report
+	lastSuccess := s.lastSuccess
+	s.mu.RUnlock()
+	if r == nil {
+		return
+	}
+	for _, g := range newReportGauges(r) {
+		g.Collect(ch)
+	}
+	if !lastSuccess.IsZero() {
+		newLastSuccessGauge(lastSuccess).Collect(ch)
+	}
+}
+
+func newServeHandler(state *serveState) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(state, state.listErrors, state.objectsScanned, newBuildInfoGauge())
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !state.ready() {
+			http.Error(w, "the first scan has not completed", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+	return mux
+}
+
+// runScans scans the cluster every interval until ctx is canceled.
+// A failed scan is logged and the result of the previous scan is kept.
+func runScans(ctx context.Context, s *scanner, state *serveState, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r, err := s.scan(ctx)
+		if err != nil {
+			log.Printf("scan failed: %v", err)
+		} else {
+			state.update(r)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func serveMain(cmd *cobra.Command, args []string) error {
+	if intervalFlag <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	s, err := newScanner(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	state := newServeState()
+	server := &http.Server{
+		Addr:              listenAddressFlag,
+		Handler:           newServeHandler(state),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	go runScans(ctx, s, state, intervalFlag)
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestServeHandler(t *testing.T) {
+	t.Parallel()
+	state := newServeState()
+	server := httptest.NewServer(newServeHandler(state))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, _ := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	code, body := get("/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body, "zombie_duration_seconds")
+
+	state.update(newTestReport())
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	code, body = get("/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	var durations, suppressed, finalizers int
+	for _, line := range strings.Split(body, "\n") {
+		switch {
+		case strings.HasPrefix(line, "zombie_duration_seconds{"):
+			durations++
+		case strings.HasPrefix(line, "zombie_suppressed_duration_seconds{"):
+			suppressed++
+		case strings.HasPrefix(line, "zombie_finalizer_info{"):
+			finalizers++
+		}
+	}
+	assert.Equal(t, 3, durations)
+	assert.Equal(t, 1, suppressed)
+	assert.Equal(t, 3, finalizers)
+	assert.Contains(t, body, `finalizer="example.com/cleanup",kind="Pod",manager="cleanup-operator",name="pod-a",namespace="test",rule="pods"`)
+	assert.Contains(t, body, "\nzombie_detector_discovery_failures 0\n")
+	assert.NotContains(t, body, "updated_at")
+	assert.Contains(t, body, `zombie_resources_total{group="",kind="Pod",namespace="test"} 2`)
+	assert.Contains(t, body, `zombie_resources_total{group="apps",kind="Deployment",namespace="test"} 1`)
+	assert.Contains(t, body, `zombie_oldest_duration_seconds{kind="Pod"} 7200`)
+	assert.Contains(t, body, `zombie_oldest_duration_seconds{kind="Deployment"} 10800`)
+	assert.Contains(t, body, "\nzombie_detector_last_success_timestamp_seconds 1.704164655e+09\n")
+	assert.Contains(t, body, "\nzombie_detector_scan_duration_seconds 10\n")
+	assert.Contains(t, body, `zombie_detector_build_info{goversion="`+runtime.Version()+`",version="`+version+`"} 1`)
+
+	// An incomplete scan does not advance the time of the last success.
+	r := newTestReport()
+	r.Scan.EndTime = r.Scan.EndTime.Add(time.Hour)
+	r.addFailures(scanFailures{lists: []listFailure{{Version: "v1", Resource: "secrets", Reason: "Forbidden"}}})
+	r.objectsScanned = map[schema.GroupResource]int{
+		{Resource: "pods"}:                       5,
+		{Group: "apps", Resource: "deployments"}: 2,
+	}
+	state.update(r)
+	state.update(r)
+	_, body = get("/metrics")
+	assert.Contains(t, body, `zombie_detector_list_errors_total{group="",reason="Forbidden",resource="secrets"} 2`)
+	assert.Contains(t, body, `zombie_detector_objects_scanned_total{group="",resource="pods"} 10`)
+	assert.Contains(t, body, `zombie_detector_objects_scanned_total{group="apps",resource="deployments"} 4`)
+	assert.Contains(t, body, "\nzombie_detector_last_success_timestamp_seconds 1.704164655e+09\n")
+}
@@ -26,8 +26,22 @@ var testEnv *envtest.Environment
 var scheme = runtime.NewScheme()
 
 var testThreshold time.Duration
+var testRules thresholdRules
 var cancelCluster context.CancelFunc
 
+// getAllResources returns all scanned objects.
+// It collects the whole cluster in memory, so it is only for tests.
+func getAllResources(ctx context.Context, config *rest.Config, opts scanOptions) ([]resourceMetadata, error) {
+	resources := make([]resourceMetadata, 0)
+	_, err := listResources(ctx, config, opts, func(res resourceMetadata) {
+		resources = append(resources, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
 func TestClient(t *testing.T) {
 	RegisterFailHandler(Fail)
 	SetDefaultEventuallyTimeout(1 * time.Minute)
@@ -42,6 +56,7 @@ var _ = BeforeSuite(func() {
 
 	testThreshold, err = time.ParseDuration("5s")
 	Expect(err).NotTo(HaveOccurred())
+	testRules = thresholdRules{fallback: testThreshold}
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{}
@@ -67,6 +82,7 @@ var _ = BeforeSuite(func() {
 	testPod.Name = "test-pod"
 	testPod.Namespace = "test"
 	testPod.Finalizers = []string{"kubernetes"}
+	testPod.Labels = map[string]string{"app": "test"}
 	testPod.Spec.Containers = []corev1.Container{{Name: "c1", Image: "nginx"}}
 
 	testConfigMap := v1.ConfigMap{}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// ignoreAnnotation suppresses a zombie if its value is "true".
+	ignoreAnnotation = "zombie-detector.cybozu.io/ignore"
+	// ignoreUntilAnnotation suppresses a zombie until the time in RFC3339.
+	ignoreUntilAnnotation = "zombie-detector.cybozu.io/ignore-until"
+)
+
+// suppressedBy returns a description of the annotation that suppresses a zombie, or an empty string if it is not suppressed.
+func suppressedBy(annotations map[string]string, now time.Time) (string, error) {
+	if annotations[ignoreAnnotation] == "true" {
+		return ignoreAnnotation, nil
+	}
+	value, ok := annotations[ignoreUntilAnnotation]
+	if !ok {
+		return "", nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s annotation %q: %w", ignoreUntilAnnotation, value, err)
+	}
+	if now.Before(until) {
+		return ignoreUntilAnnotation, nil
+	}
+	return "", nil
+}
+
+// getNamespaceAnnotations returns the annotations of the namespaces of zombies.
+// If a namespace cannot be read, for example without the permission to get namespaces, its annotations are treated as unknown
+// and the failure is reported to stderr, so that zombies in it are still reported without suppression.
+func getNamespaceAnnotations(ctx context.Context, dynamicClient dynamic.Interface, zombieResources []resourceMetadata) map[string]map[string]string {
+	gvr := corev1.SchemeGroupVersion.WithResource("namespaces")
+	nsAnnotations := make(map[string]map[string]string)
+	for _, res := range zombieResources {
+		if res.namespace == "" {
+			continue
+		}
+		if _, ok := nsAnnotations[res.namespace]; ok {
+			continue
+		}
+		ns, err := dynamicClient.Resource(gvr).Get(ctx, res.namespace, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			nsAnnotations[res.namespace] = nil
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Namespace %s: failed to get annotations: %v\n", res.namespace, err)
+			nsAnnotations[res.namespace] = nil
+			continue
+		}
+		nsAnnotations[res.namespace] = ns.GetAnnotations()
+	}
+	return nsAnnotations
+}
+
+// splitSuppressedResources separates zombies suppressed by annotations on themselves or their namespaces.
+func splitSuppressedResources(zombieResources []resourceMetadata, nsAnnotations map[string]map[string]string, now time.Time) ([]resourceMetadata, []resourceMetadata) {
+	active := make([]resourceMetadata, 0, len(zombieResources))
+	suppressed := make([]resourceMetadata, 0)
+	for _, res := range zombieResources {
+		by, err := suppressedBy(res.annotations, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s/%s: %v\n", res.kind, res.namespace, res.name, err)
+		}
+		if by == "" && res.namespace != "" {
+			by, err = suppressedBy(nsAnnotations[res.namespace], now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Namespace %s: %v\n", res.namespace, err)
+			}
+			if by != "" {
+				by = fmt.Sprintf("%s on Namespace %s", by, res.namespace)
+			}
+		}
+		if by == "" {
+			active = append(active, res)
+			continue
+		}
+		res.suppressedBy = by
+		suppressed = append(suppressed, res)
+	}
+	return active, suppressed
+}
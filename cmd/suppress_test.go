@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSuppressedBy(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		name        string
+		annotations map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        "",
+		},
+		{
+			name:        "ignore",
+			annotations: map[string]string{ignoreAnnotation: "true"},
+			want:        ignoreAnnotation,
+		},
+		{
+			name:        "ignore is false",
+			annotations: map[string]string{ignoreAnnotation: "false"},
+			want:        "",
+		},
+		{
+			name:        "ignore until future",
+			annotations: map[string]string{ignoreUntilAnnotation: "2024-01-03T00:00:00Z"},
+			want:        ignoreUntilAnnotation,
+		},
+		{
+			name:        "ignore until past",
+			annotations: map[string]string{ignoreUntilAnnotation: "2024-01-02T00:00:00+09:00"},
+			want:        "",
+		},
+		{
+			name:        "invalid ignore until",
+			annotations: map[string]string{ignoreUntilAnnotation: "tomorrow"},
+			want:        "",
+			wantErr:     true,
+		},
+	} {
+		got, err := suppressedBy(tt.annotations, now)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
+
+func TestSplitSuppressedResources(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	zombies := []resourceMetadata{
+		{kind: "Pod", name: "active", namespace: "default"},
+		{kind: "Pod", name: "ignored", namespace: "default", annotations: map[string]string{ignoreAnnotation: "true"}},
+		{kind: "Pod", name: "in-ignored-namespace", namespace: "draining"},
+		{kind: "PersistentVolume", name: "cluster-scoped"},
+	}
+	nsAnnotations := map[string]map[string]string{
+		"default":  nil,
+		"draining": {ignoreUntilAnnotation: now.Add(time.Hour).Format(time.RFC3339)},
+	}
+	active, suppressed := splitSuppressedResources(zombies, nsAnnotations, now)
+	require.Len(t, active, 2)
+	assert.Equal(t, "active", active[0].name)
+	assert.Equal(t, "cluster-scoped", active[1].name)
+	require.Len(t, suppressed, 2)
+	assert.Equal(t, "ignored", suppressed[0].name)
+	assert.Equal(t, ignoreAnnotation, suppressed[0].suppressedBy)
+	assert.Equal(t, "in-ignored-namespace", suppressed[1].name)
+	assert.Equal(t, ignoreUntilAnnotation+" on Namespace draining", suppressed[1].suppressedBy)
+}
+
+func TestGetNamespaceAnnotations(t *testing.T) {
+	t.Parallel()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	client := dynamicfake.NewSimpleDynamicClient(scheme,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "annotated", Annotations: map[string]string{ignoreAnnotation: "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "restricted"}},
+	)
+	client.PrependReactor("get", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.GetAction).GetName() != "restricted" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(corev1.Resource("namespaces"), "restricted", errors.New("forbidden"))
+	})
+
+	got := getNamespaceAnnotations(context.Background(), client, []resourceMetadata{
+		{kind: "Pod", name: "a", namespace: "annotated"},
+		{kind: "Pod", name: "b", namespace: "restricted"},
+		{kind: "Pod", name: "c", namespace: "deleted"},
+		{kind: "Namespace", name: "annotated"},
+	})
+	// Namespaces that cannot be read are treated as having no annotations.
+	assert.Equal(t, map[string]map[string]string{
+		"annotated":  {ignoreAnnotation: "true"},
+		"restricted": nil,
+		"deleted":    nil,
+	}, got)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var textfileFlag string
+
+func init() {
+	rootCmd.Flags().StringVar(&textfileFlag, "textfile", "", "path to the file to write the metrics in the text format for the textfile collector of node_exporter, such as /var/lib/node_exporter/zombies.prom. If this flag is given, the result is not printed to stdout unless --output is given")
+}
+
+// checkTextfileDir returns an error if the directory of the textfile does not exist, so that it fails before scanning.
+func checkTextfileDir(filename string) error {
+	dir := filepath.Dir(filename)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+// writeZombieResourcesMetrics writes the metrics of the report to filename in the text format.
+// The metrics are written to a temporary file in the same directory and renamed to filename,
+// so that the textfile collector never reads a partially written file.
+func writeZombieResourcesMetrics(r *report, filename string) error {
+	return prometheus.WriteToTextfile(filename, newReportRegistry(r))
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteZombieResourcesMetrics(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "zombies.prom")
+	require.NoError(t, os.WriteFile(filename, []byte("stale\n"), 0644))
+
+	require.NoError(t, writeZombieResourcesMetrics(newTestReport(), filename))
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	parser := expfmt.NewTextParser(model.LegacyValidation)
+	families, err := parser.TextToMetricFamilies(f)
+	require.NoError(t, err)
+	assert.Len(t, families["zombie_duration_seconds"].GetMetric(), 3)
+	assert.Len(t, families["zombie_suppressed_duration_seconds"].GetMetric(), 1)
+	assert.Contains(t, families, "zombie_detector_last_success_timestamp_seconds")
+	assert.Contains(t, families, "zombie_detector_build_info")
+
+	// An incomplete scan does not report the time of the last success.
+	r := newTestReport()
+	r.addFailures(scanFailures{lists: []listFailure{{Version: "v1", Resource: "secrets", Reason: "Forbidden"}}})
+	gathered, err := newReportRegistry(r).Gather()
+	require.NoError(t, err)
+	for _, mf := range gathered {
+		assert.NotEqual(t, "zombie_detector_last_success_timestamp_seconds", mf.GetName())
+	}
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	// The temporary file is renamed to the textfile.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestCheckTextfileDir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	assert.NoError(t, checkTextfileDir(filepath.Join(dir, "zombies.prom")))
+	assert.Error(t, checkTextfileDir(filepath.Join(dir, "missing", "zombies.prom")))
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	assert.Error(t, checkTextfileDir(filepath.Join(file, "zombies.prom")))
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// defaultThresholdRule is the name of the rule applied when no rule in the configuration file matches.
+const defaultThresholdRule = "default"
+
+type thresholdRule struct {
+	name      string
+	group     *namePattern
+	kind      *namePattern
+	namespace *namePattern
+	selector  labels.Selector
+	threshold time.Duration
+}
+
+func (r *thresholdRule) match(res resourceMetadata) bool {
+	if r.group != nil {
+		gv, err := schema.ParseGroupVersion(res.version)
+		if err != nil || !r.group.match(gv.Group) {
+			return false
+		}
+	}
+	if r.kind != nil && !r.kind.match(res.kind) {
+		return false
+	}
+	if r.namespace != nil && !r.namespace.match(res.namespace) {
+		return false
+	}
+	if r.selector != nil && !r.selector.Matches(labels.Set(res.labels)) {
+		return false
+	}
+	return true
+}
+
+// parseOptionalNamePattern returns nil for an empty string, which means any name.
+func parseOptionalNamePattern(s string) (*namePattern, error) {
+	if s == "" {
+		return nil, nil
+	}
+	p, err := parseNamePattern(s)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// thresholdRules decides the threshold of detection for each object.
+// The first matching rule is applied, and fallback is applied when no rule matches.
+type thresholdRules struct {
+	rules    []thresholdRule
+	fallback time.Duration
+}
+
+func newThresholdRules(fallback time.Duration, configs []thresholdRuleConfig) (thresholdRules, error) {
+	rules := thresholdRules{fallback: fallback}
+	seen := map[string]bool{defaultThresholdRule: true}
+	for i, c := range configs {
+		if c.Name == "" {
+			return rules, fmt.Errorf("name of thresholds[%d] must not be empty", i)
+		}
+		if seen[c.Name] {
+			return rules, fmt.Errorf("duplicate threshold rule name %q", c.Name)
+		}
+		seen[c.Name] = true
+		if c.Threshold.Duration <= 0 {
+			return rules, fmt.Errorf("threshold of rule %q must be positive", c.Name)
+		}
+
+		rule := thresholdRule{name: c.Name, threshold: c.Threshold.Duration}
+		if c.Group != "" {
+			group := c.Group
+			if group == "core" {
+				group = ""
+			}
+			p, err := parseNamePattern(group)
+			if err != nil {
+				return rules, fmt.Errorf("invalid group of threshold rule %q: %w", c.Name, err)
+			}
+			rule.group = &p
+		}
+		var err error
+		if rule.kind, err = parseOptionalNamePattern(c.Kind); err != nil {
+			return rules, fmt.Errorf("invalid kind of threshold rule %q: %w", c.Name, err)
+		}
+		if rule.namespace, err = parseOptionalNamePattern(c.Namespace); err != nil {
+			return rules, fmt.Errorf("invalid namespace of threshold rule %q: %w", c.Name, err)
+		}
+		if c.Selector != "" {
+			selector, err := labels.Parse(c.Selector)
+			if err != nil {
+				return rules, fmt.Errorf("invalid selector of threshold rule %q: %w", c.Name, err)
+			}
+			rule.selector = selector
+		}
+		rules.rules = append(rules.rules, rule)
+	}
+	return rules, nil
+}
+
+// lookup returns the name of the matched rule and its threshold.
+func (r *thresholdRules) lookup(res resourceMetadata) (string, time.Duration) {
+	for i := range r.rules {
+		if r.rules[i].match(res) {
+			return r.rules[i].name, r.rules[i].threshold
+		}
+	}
+	return defaultThresholdRule, r.fallback
+}
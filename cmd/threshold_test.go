@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestThresholdRules(t *testing.T) {
+	t.Parallel()
+	rules, err := newThresholdRules(24*time.Hour, []thresholdRuleConfig{
+		{
+			Name:      "critical-pods",
+			Group:     "core",
+			Kind:      "Pod",
+			Selector:  "tier=critical",
+			Threshold: metav1.Duration{Duration: time.Minute},
+		},
+		{
+			Name:      "tenant-pods",
+			Group:     "core",
+			Kind:      "Pod",
+			Namespace: "tenant-*",
+			Threshold: metav1.Duration{Duration: 10 * time.Minute},
+		},
+		{
+			Name:      "volumes",
+			Kind:      "PersistentVolume*",
+			Threshold: metav1.Duration{Duration: 48 * time.Hour},
+		},
+		{
+			Name:      "example",
+			Group:     "/\\.example\\.com$/",
+			Threshold: metav1.Duration{Duration: time.Hour},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name          string
+		resource      resourceMetadata
+		wantRule      string
+		wantThreshold time.Duration
+	}{
+		{
+			name:          "pod with labels",
+			resource:      resourceMetadata{version: "v1", kind: "Pod", namespace: "tenant-a", labels: map[string]string{"tier": "critical"}},
+			wantRule:      "critical-pods",
+			wantThreshold: time.Minute,
+		},
+		{
+			name:          "pod in tenant namespace",
+			resource:      resourceMetadata{version: "v1", kind: "Pod", namespace: "tenant-a"},
+			wantRule:      "tenant-pods",
+			wantThreshold: 10 * time.Minute,
+		},
+		{
+			name:          "pod in other namespace",
+			resource:      resourceMetadata{version: "v1", kind: "Pod", namespace: "default"},
+			wantRule:      defaultThresholdRule,
+			wantThreshold: 24 * time.Hour,
+		},
+		{
+			name:          "pod-like resource in other group",
+			resource:      resourceMetadata{version: "example.com/v1", kind: "Pod", namespace: "tenant-a"},
+			wantRule:      defaultThresholdRule,
+			wantThreshold: 24 * time.Hour,
+		},
+		{
+			name:          "cluster-scoped resource",
+			resource:      resourceMetadata{version: "v1", kind: "PersistentVolume"},
+			wantRule:      "volumes",
+			wantThreshold: 48 * time.Hour,
+		},
+		{
+			name:          "regular expression of group",
+			resource:      resourceMetadata{version: "widgets.example.com/v1", kind: "Widget", namespace: "default"},
+			wantRule:      "example",
+			wantThreshold: time.Hour,
+		},
+	} {
+		rule, threshold := rules.lookup(tt.resource)
+		assert.Equal(t, tt.wantRule, rule, tt.name)
+		assert.Equal(t, tt.wantThreshold, threshold, tt.name)
+	}
+}
+
+func TestThresholdRulesError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		configs []thresholdRuleConfig
+	}{
+		{
+			name:    "empty name",
+			configs: []thresholdRuleConfig{{Threshold: metav1.Duration{Duration: time.Hour}}},
+		},
+		{
+			name: "duplicate name",
+			configs: []thresholdRuleConfig{
+				{Name: "a", Threshold: metav1.Duration{Duration: time.Hour}},
+				{Name: "a", Threshold: metav1.Duration{Duration: time.Hour}},
+			},
+		},
+		{
+			name:    "reserved name",
+			configs: []thresholdRuleConfig{{Name: defaultThresholdRule, Threshold: metav1.Duration{Duration: time.Hour}}},
+		},
+		{
+			name:    "no threshold",
+			configs: []thresholdRuleConfig{{Name: "a"}},
+		},
+		{
+			name:    "invalid kind",
+			configs: []thresholdRuleConfig{{Name: "a", Kind: "[", Threshold: metav1.Duration{Duration: time.Hour}}},
+		},
+		{
+			name:    "invalid selector",
+			configs: []thresholdRuleConfig{{Name: "a", Selector: "a b c", Threshold: metav1.Duration{Duration: time.Hour}}},
+		},
+	} {
+		_, err := newThresholdRules(time.Hour, tt.configs)
+		assert.Error(t, err, tt.name)
+	}
+}
+
+func TestDetectZombieResourcesWithRules(t *testing.T) {
+	t.Parallel()
+	rules, err := newThresholdRules(24*time.Hour, []thresholdRuleConfig{
+		{Name: "pods", Group: "core", Kind: "Pod", Threshold: metav1.Duration{Duration: 10 * time.Minute}},
+	})
+	require.NoError(t, err)
+
+	deletedAt := &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	zombies := detectZombieResources([]resourceMetadata{
+		{version: "v1", kind: "Pod", name: "pod", namespace: "test", deletionTimestamp: deletedAt},
+		{version: "v1", kind: "ConfigMap", name: "cm", namespace: "test", deletionTimestamp: deletedAt},
+		{version: "v1", kind: "Pod", name: "alive", namespace: "test"},
+	}, rules)
+	require.Len(t, zombies, 1)
+	assert.Equal(t, "pod", zombies[0].name)
+	assert.Equal(t, "pods", zombies[0].rule)
+	assert.Equal(t, 10*time.Minute, zombies[0].threshold)
+}
@@ -40,10 +40,12 @@ var _ = Describe("zombie-detector e2e test", func() {
 			}
 			return nil
 		}).Should(Succeed())
-		By("checking metrics is empty")
+		By("checking metrics has no zombies")
 		res, err := getMetricsFromPushgateway()
 		Expect(err).NotTo(HaveOccurred())
-		Expect(res.Data).To(BeEmpty())
+		index, err := returnZombieDetectorMetricsIndex(*res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Data[index].ZombieDurationSeconds.Metrics).To(BeEmpty())
 	})
 
 	It("should skipping ignored resources", func() {
@@ -182,10 +184,12 @@ var _ = Describe("zombie-detector e2e test", func() {
 			return nil
 		}).Should(Succeed())
 
-		By("checking metrics is empty")
+		By("checking metrics has no zombies")
 		res, err := getMetricsFromPushgateway()
 		Expect(err).NotTo(HaveOccurred())
-		Expect(res.Data).To(BeEmpty())
+		index, err := returnZombieDetectorMetricsIndex(*res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Data[index].ZombieDurationSeconds.Metrics).To(BeEmpty())
 	})
 
 })
@@ -223,13 +227,12 @@ type Response struct {
 		ZombieDurationSeconds struct {
 			Metrics []struct {
 				Labels struct {
-					APIVersion string    `json:"apiVersion"`
-					Instance   string    `json:"instance"`
-					Job        string    `json:"job"`
-					Kind       string    `json:"kind"`
-					Name       string    `json:"name"`
-					Namespace  string    `json:"namespace"`
-					UpdatedAt  time.Time `json:"updated_at"`
+					APIVersion string `json:"apiVersion"`
+					Instance   string `json:"instance"`
+					Job        string `json:"job"`
+					Kind       string `json:"kind"`
+					Name       string `json:"name"`
+					Namespace  string `json:"namespace"`
 				} `json:"labels"`
 				Value string `json:"value"`
 			} `json:"metrics"`